@@ -0,0 +1,232 @@
+package generator
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+)
+
+// The functions below expose the generator to Go programs, e.g. linters or
+// test harnesses asserting on the rendered table or schema. Generate runs the
+// whole pipeline on in-memory inputs; the others are its individual steps.
+// Apart from values.yaml (ParseMetadata), @include and fromFile files they do
+// no file I/O. A nil *Config stands for DefaultConfig(), and the caller's
+// config is never modified. Errors are returned; the messages the command
+// prints go to Options.Log for Generate and are discarded by the others.
+// Calls are serialized, as they share the logger.
+
+// Options are the inputs of Generate.
+type Options struct {
+	// Values is the content of values.yaml, or of a values.json.
+	Values []byte
+	// Metadata, when set, holds the metadata comments instead of Values,
+	// e.g. for JSON values, which cannot have comments.
+	Metadata []byte
+	// Readme is the current README.md, whose parameters section is
+	// replaced; when nil no README is rendered.
+	Readme []byte
+	// Schema requests the schema in Result.Schema.
+	Schema bool
+	// Config defaults to DefaultConfig().
+	Config *Config
+	// Dir is the directory that @include and fromFile paths are relative
+	// to, as the directory of values.yaml is for the command.
+	Dir string
+	// Log receives the messages the command prints; they are discarded
+	// when it is nil.
+	Log io.Writer
+}
+
+// Result holds the outputs of Generate.
+type Result struct {
+	// Readme is the updated README.md, empty unless Options.Readme is set.
+	Readme string
+	// Schema is the schema as the command writes it with --schema, nil
+	// unless Options.Schema is set.
+	Schema []byte
+	// Warnings counts the warnings logged, which --strict turns into
+	// errors.
+	Warnings int
+	// Keys lists the keys whose metadata does not match the values, such
+	// as orphan metadata, which is only a warning. When keys lack metadata
+	// Generate fails instead, with a *KeyError holding the same lists.
+	Keys KeyReport
+}
+
+// apiMu serializes the API calls, which log through the package's console.
+var apiMu sync.Mutex
+
+// logTo runs fn with console writing to w, or discarding when w is nil, and
+// returns the number of warnings fn logged.
+func logTo(w io.Writer, fn func() error) (int, error) {
+	apiMu.Lock()
+	defer apiMu.Unlock()
+	if w == nil {
+		w = io.Discard
+	}
+	saved := console
+	console = &logger{w: w}
+	defer func() { console = saved }()
+	err := fn()
+	return console.warnings, err
+}
+
+// Generate does what the command does for --readme and --schema without
+// touching the files: it validates the metadata of opts.Values and returns the
+// updated README and the schema. Validation errors are returned, with an
+// empty Result, like the command fails before writing anything.
+func Generate(opts Options) (Result, error) {
+	if opts.Readme == nil && !opts.Schema {
+		return Result{}, errors.New("nothing to generate: set Options.Readme and/or Options.Schema")
+	}
+	var res Result
+	warnings, err := logTo(opts.Log, func() (err error) {
+		res, err = generate(opts)
+		return err
+	})
+	res.Warnings = warnings
+	if err != nil {
+		return Result{}, err
+	}
+	return res, nil
+}
+
+// orDefault returns cfg, or DefaultConfig() when it is nil.
+func orDefault(cfg *Config) *Config {
+	if cfg == nil {
+		return DefaultConfig()
+	}
+	return cfg
+}
+
+// generate is Generate once the logger is set up.
+func generate(opts Options) (Result, error) {
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = DefaultConfig()
+	} else {
+		// Folding the deprecated columns must not change the caller's config;
+		// it only replaces fields, so a shallow copy is enough.
+		c := *cfg
+		cfg = &c
+		foldDeprecatedColumns(cfg)
+		if err := validateConfig(cfg); err != nil {
+			return Result{}, err
+		}
+	}
+	files := []valuesFile{{path: filepath.Join(opts.Dir, "values.yaml"), data: opts.Values}}
+	var comments []valuesFile
+	if opts.Metadata != nil {
+		comments = []valuesFile{{path: filepath.Join(opts.Dir, "metadata.yaml"), data: opts.Metadata}}
+	}
+	meta, err := getParsedMetadata(files, comments, cfg)
+	if err != nil {
+		return Result{}, err
+	}
+
+	res := Result{Keys: meta.Keys}
+	if opts.Readme != nil {
+		secs, err := renderedSections(meta, cfg)
+		if err != nil {
+			return Result{}, err
+		}
+		readme, err := insertReadmeTable(opts.Readme, secs, cfg, nil)
+		if err != nil {
+			return Result{}, err
+		}
+		res.Readme = string(readme)
+	}
+	if opts.Schema {
+		schema, err := buildSchema(meta, cfg)
+		if err != nil {
+			return Result{}, err
+		}
+		res.Schema = schemaJSON(schema)
+	}
+	return res, nil
+}
+
+// ParseMetadata reads valuesPath and returns its metadata merged with the
+// actual values. As with the command, validation errors are returned together
+// with the metadata; a nil Metadata means the file could not be processed.
+func ParseMetadata(valuesPath string, cfg *Config) (*Metadata, error) {
+	cfg = orDefault(cfg)
+	data, err := ioutil.ReadFile(valuesPath)
+	if err != nil {
+		return nil, err
+	}
+	var meta *Metadata
+	_, err = logTo(nil, func() (err error) {
+		meta, err = getParsedMetadata([]valuesFile{{valuesPath, data}}, nil, cfg)
+		return err
+	})
+	return meta, err
+}
+
+// RenderReadmeTable returns the Markdown that the command places below the
+// parameters heading. heading is the prefix of the section headings, e.g.
+// "###" under a "## Parameters" heading. meta is not modified.
+func RenderReadmeTable(meta *Metadata, heading string, cfg *Config) (string, error) {
+	cfg = orDefault(cfg)
+	var md string
+	_, err := logTo(nil, func() error {
+		secs, err := renderedSections(meta, cfg)
+		if err != nil {
+			return err
+		}
+		md = renderReadmeTable(secs, heading, cfg, nil)
+		return nil
+	})
+	return md, err
+}
+
+// renderedSections returns copies of the sections of meta with their
+// parameters prepared for rendering.
+func renderedSections(meta *Metadata, cfg *Config) ([]*Section, error) {
+	secs := make([]*Section, 0, len(meta.Sections))
+	for _, sec := range meta.Sections {
+		params, err := buildParamsToRender(cloneParameters(sec.Parameters), cfg)
+		if err != nil {
+			return nil, err
+		}
+		sortSectionParameters(params, cfg)
+		secs = append(secs, &Section{Name: sec.Name, DescriptionLines: sec.DescriptionLines, Parameters: params,
+			Level: sec.Level, Skipped: sec.Skipped})
+	}
+	return secs, nil
+}
+
+// BuildSchema returns the schema that the command writes with --schema.
+// meta is not modified.
+func BuildSchema(meta *Metadata, cfg *Config) (SchemaObject, error) {
+	cfg = orDefault(cfg)
+	var schema SchemaObject
+	_, err := logTo(nil, func() (err error) {
+		schema, err = buildSchema(meta, cfg)
+		return err
+	})
+	return schema, err
+}
+
+// buildSchema is BuildSchema once the logger is set up.
+func buildSchema(meta *Metadata, cfg *Config) (SchemaObject, error) {
+	params, err := buildParamsToRender(cloneParameters(meta.Parameters), cfg)
+	if err != nil {
+		return nil, err
+	}
+	return buildOpenAPISchema(params, meta.RootType, cfg)
+}
+
+// cloneParameters copies the parameters so that applying modifiers leaves
+// the caller's metadata untouched.
+func cloneParameters(list []*Parameter) []*Parameter {
+	out := make([]*Parameter, len(list))
+	for i, p := range list {
+		c := *p
+		c.Modifiers = append([]string(nil), p.Modifiers...)
+		out[i] = &c
+	}
+	return out
+}
@@ -0,0 +1,339 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   string
+		warnings int
+		log      string
+	}{
+		{
+			name:   "clean",
+			values: "## @section S\n## @param a A\na: 1\n",
+			log:    "INFO: Metadata is correct!",
+		},
+		{
+			name:     "orphan metadata",
+			values:   "## @section S\n## @param a A\na: 1\n## @param gone G\n",
+			warnings: 1,
+			log:      "WARNING: Metadata provided for non existing key: gone",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log strings.Builder
+			res, err := Generate(Options{Values: []byte(tt.values), Schema: true, Log: &log})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if res.Warnings != tt.warnings {
+				t.Errorf("Warnings = %d, want %d", res.Warnings, tt.warnings)
+			}
+			if !strings.Contains(log.String(), tt.log) {
+				t.Errorf("log %q does not contain %q", log.String(), tt.log)
+			}
+		})
+	}
+}
+
+func TestGenerateDiscardsLogByDefault(t *testing.T) {
+	if _, err := Generate(Options{Values: []byte("## @param gone G\n"), Schema: true}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if console.w != io.Discard || console.warnings != 0 {
+		t.Errorf("the package logger was changed by Generate: %+v", console)
+	}
+}
+
+func TestRenderFromParsedMetadata(t *testing.T) {
+	const values = "## @section Settings\n## @param replicas Replicas\nreplicas: 1\n## @param hosts [array] Hosts\nhosts: [a]\n"
+	dir := writeFiles(t, map[string]string{"values.yaml": values})
+	cfg := DefaultConfig()
+	meta, err := ParseMetadata(filepath.Join(dir, "values.yaml"), cfg)
+	if err != nil {
+		t.Fatalf("ParseMetadata: %v", err)
+	}
+	before, _ := json.Marshal(meta)
+
+	want, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Schema: true, Config: cfg})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	tests := []struct {
+		name string
+		cfg  *Config
+		want string
+	}{
+		{name: "default", cfg: cfg, want: strings.TrimPrefix(want.Readme, readmeHeading)},
+		{name: "compact", cfg: func() *Config { c := DefaultConfig(); c.Readme.Compact = true; return c }(),
+			want: "\n| Section  | Name       | Description | Value |\n| -------- | ---------- | ----------- | ----- |\n" +
+				"| Settings | `replicas` | Replicas    | `1`   |\n| Settings | `hosts`    | Hosts       | `[]`  |\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := RenderReadmeTable(meta, "###", tt.cfg)
+			if err != nil {
+				t.Fatalf("RenderReadmeTable: %v", err)
+			}
+			if table != tt.want {
+				t.Errorf("table:\n%q\nwant:\n%q", table, tt.want)
+			}
+		})
+	}
+
+	schema, err := BuildSchema(meta, cfg)
+	if err != nil {
+		t.Fatalf("BuildSchema: %v", err)
+	}
+	if got := schemaJSON(schema); string(got) != string(want.Schema) {
+		t.Errorf("BuildSchema:\n%s\nwant:\n%s", got, want.Schema)
+	}
+	if after, _ := json.Marshal(meta); string(after) != string(before) {
+		t.Errorf("rendering changed the metadata:\n%s\nwas:\n%s", after, before)
+	}
+}
+
+func TestNilConfig(t *testing.T) {
+	const values = "## @section S\n## @param a A\na: 1\n"
+	path := filepath.Join(writeFiles(t, map[string]string{"values.yaml": values}), "values.yaml")
+	meta, err := ParseMetadata(path, nil)
+	if err != nil {
+		t.Fatalf("ParseMetadata: %v", err)
+	}
+	tests := []struct {
+		name   string
+		render func(cfg *Config) (string, error)
+	}{
+		{name: "RenderReadmeTable", render: func(cfg *Config) (string, error) {
+			return RenderReadmeTable(meta, "###", cfg)
+		}},
+		{name: "BuildSchema", render: func(cfg *Config) (string, error) {
+			schema, err := BuildSchema(meta, cfg)
+			return string(schemaJSON(schema)), err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.render(nil)
+			if err != nil {
+				t.Fatalf("nil config: %v", err)
+			}
+			want, err := tt.render(DefaultConfig())
+			if err != nil {
+				t.Fatalf("default config: %v", err)
+			}
+			if got != want {
+				t.Errorf("nil config:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestGenerateKeepsConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Readme.TypeColumn = true
+	cfg.Readme.RequiredColumn = true
+	before, _ := json.Marshal(cfg)
+	res, err := Generate(Options{Values: []byte("## @section S\n## @param a A\na: 1\n"), Readme: []byte(readmeHeading), Config: cfg})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !strings.Contains(res.Readme, "| Type ") || !strings.Contains(res.Readme, "| Required ") {
+		t.Errorf("deprecated columns not rendered:\n%s", res.Readme)
+	}
+	if after, _ := json.Marshal(cfg); string(after) != string(before) {
+		t.Errorf("Generate changed the config:\n%s\nwas:\n%s", after, before)
+	}
+}
+
+func TestParseMetadataErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"values.yaml": "## @section S\na: 1\n"})
+	tests := []struct {
+		name string
+		path string
+		meta bool
+		err  string
+	}{
+		{name: "missing file", path: filepath.Join(dir, "missing.yaml"), err: "no such file"},
+		{name: "undocumented key", path: filepath.Join(dir, "values.yaml"), meta: true, err: "metadata errors found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, err := ParseMetadata(tt.path, DefaultConfig())
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+			if (meta != nil) != tt.meta {
+				t.Errorf("metadata = %v, want returned: %v", meta, tt.meta)
+			}
+		})
+	}
+}
+
+func TestSchemaDefaultsAreCopies(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		keys   []string
+	}{
+		{name: "map", values: "## @param a [object, required] A\na:\n  x: 1\n", keys: []string{"a"}},
+		{name: "list", values: "## @param a [array, required] A\na: [1]\n", keys: []string{"a"}},
+		{name: "shared through an alias", keys: []string{"base", "other"},
+			values: "## @param base [object, required] Base\nbase: &b\n  x: 1\n## @param other Other\nother: *b\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Validation.CollapseAliases = true
+			dir := writeFiles(t, map[string]string{"values.yaml": "## @section S\n" + tt.values})
+			meta, err := ParseMetadata(filepath.Join(dir, "values.yaml"), cfg)
+			if err != nil {
+				t.Fatalf("ParseMetadata: %v", err)
+			}
+			before, _ := json.Marshal(meta)
+			schema, err := BuildSchema(meta, cfg)
+			if err != nil {
+				t.Fatalf("BuildSchema: %v", err)
+			}
+			props := schema["properties"].(SchemaObject)
+			switch d := props[tt.keys[0]].(SchemaObject)["default"].(type) {
+			case map[string]interface{}:
+				d["x"] = "changed"
+			case []interface{}:
+				d[0] = "changed"
+			default:
+				t.Fatalf("default %v is neither a map nor a list", d)
+			}
+			if after, _ := json.Marshal(meta); string(after) != string(before) {
+				t.Errorf("editing the schema default changed the metadata:\n%s\nwas:\n%s", after, before)
+			}
+			for _, k := range tt.keys[1:] {
+				if d := props[k].(SchemaObject)["default"]; strings.Contains(fmt.Sprint(d), "changed") {
+					t.Errorf("editing the default of %s changed %s: %v", tt.keys[0], k, d)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONValues(t *testing.T) {
+	const values = `{"image": {"repository": "nginx", "tag": "1.25"}, "replicas": 2, "hosts": []}`
+	tests := []struct {
+		name     string
+		metadata string
+		rows     map[string]string
+		log      string
+	}{
+		{
+			name:     "sidecar",
+			metadata: "## @section Image\n## @param image.repository Repository\n## @param image.tag Tag\n## @param replicas Replicas\n## @param hosts Hosts\n",
+			rows:     map[string]string{"image.repository": "`nginx`", "image.tag": "`1.25`", "replicas": "`2`", "hosts": "`[]`"},
+		},
+		{
+			name:     "missing metadata",
+			metadata: "## @section Image\n## @param image.repository Repository\n## @param image.tag Tag\n## @param hosts Hosts\n",
+			log:      "values.yaml:1: ERROR: Missing metadata for key: replicas",
+		},
+		{
+			name:     "orphan metadata",
+			metadata: "## @section Image\n## @param image.repository Repository\n## @param image.tag Tag\n## @param replicas Replicas\n## @param hosts Hosts\n## @param gone Gone\n",
+			rows:     map[string]string{"replicas": "`2`"},
+			log:      "metadata.yaml:6: WARNING: Metadata provided for non existing key: gone",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log strings.Builder
+			res, err := Generate(Options{Values: []byte(values), Metadata: []byte(tt.metadata), Readme: []byte(readmeHeading),
+				Schema: true, Config: DefaultConfig(), Log: &log})
+			if !strings.Contains(log.String(), tt.log) {
+				t.Errorf("log does not contain %q:\n%s", tt.log, log.String())
+			}
+			if tt.rows == nil {
+				if err == nil {
+					t.Error("Generate succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v\n%s", err, log.String())
+			}
+			for key, want := range tt.rows {
+				if got := tableCells(tableRow(t, res.Readme, key))[2]; got != want {
+					t.Errorf("%s: value %s, want %s", key, got, want)
+				}
+			}
+			if got := property(t, res.Schema, "replicas")["type"]; got != "integer" {
+				t.Errorf("replicas: schema type %v, want integer", got)
+			}
+		})
+	}
+
+	t.Run("no sidecar", func(t *testing.T) {
+		var log strings.Builder
+		_, err := Generate(Options{Values: []byte(values), Schema: true, Config: DefaultConfig(), Log: &log})
+		if err == nil || !strings.Contains(log.String(), "Missing metadata for key: image.repository") {
+			t.Errorf("error %v, log:\n%s", err, log.String())
+		}
+	})
+}
+
+func TestKeyReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  string
+		noFail  bool
+		missing []string
+		orphan  []string
+		fails   bool
+	}{
+		{name: "matching", values: "## @param a A\na: 1\n"},
+		{name: "orphan", values: "## @param a A\n## @param gone G\na: 1\n", orphan: []string{"gone"}},
+		{name: "missing", values: "## @param a A\n## @param gone G\na: 1\nb: 2\n", missing: []string{"b"}, orphan: []string{"gone"}, fails: true},
+		{name: "missing no-fail", values: "## @param a A\na: 1\nb: 2\n", noFail: true, missing: []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Validation.NoFail = tt.noFail
+			res, err := Generate(Options{Values: []byte("## @section S\n" + tt.values), Schema: true, Config: cfg})
+			want := KeyReport{Missing: tt.missing, Orphan: tt.orphan}
+			var keyErr *KeyError
+			if tt.fails {
+				if !errors.As(err, &keyErr) {
+					t.Fatalf("error %v, want a *KeyError", err)
+				}
+				if !reflect.DeepEqual(keyErr.KeyReport, want) {
+					t.Errorf("KeyError %+v, want %+v", keyErr.KeyReport, want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if !reflect.DeepEqual(res.Keys, want) {
+				t.Errorf("Result.Keys %+v, want %+v", res.Keys, want)
+			}
+		})
+	}
+
+	t.Run("joined with other errors", func(t *testing.T) {
+		_, err := Generate(Options{Values: []byte("## @section S\n## @param a [arrray] A\na: []\nb: 2\n"), Schema: true,
+			Config: DefaultConfig()})
+		var keyErr *KeyError
+		if !errors.As(err, &keyErr) || !strings.Contains(err.Error(), "unknown modifiers") {
+			t.Errorf("error %v, want a *KeyError joined with the modifier error", err)
+		}
+	})
+}
@@ -0,0 +1,1111 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// captureLog sends the messages of the command to the returned builder until
+// the test ends.
+func captureLog(t *testing.T) *strings.Builder {
+	t.Helper()
+	var b strings.Builder
+	saved := console
+	console = &logger{w: &b}
+	t.Cleanup(func() { console = saved })
+	return &b
+}
+
+// writeFiles writes the files, by name, into a new temporary directory and
+// returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// writeChart creates a chart named name in dir/name with the given values
+// and, unless readme is empty, README.md.
+func writeChart(t *testing.T, dir, name, values, readme string) string {
+	t.Helper()
+	chart := filepath.Join(dir, "charts", name)
+	files := map[string]string{
+		"Chart.yaml":  "apiVersion: v2\nname: " + name + "\nversion: 0.1.0\n",
+		"values.yaml": values,
+	}
+	if readme != "" {
+		files["README.md"] = readme
+	}
+	if err := os.MkdirAll(chart, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(chart, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return chart
+}
+
+func TestRunChartsIndex(t *testing.T) {
+	dir := t.TempDir()
+	web := writeChart(t, dir, "web", "## @section Web\n## @param replicas Replicas\nreplicas: 1\n## @param image Image\nimage: nginx\n",
+		"# web\n\n## Parameters\n")
+	db := writeChart(t, dir, "db", "## @section DB\n## @param size Size\nsize: 1Gi\n", "")
+	index := filepath.Join(dir, "docs", "index.md")
+	if err := os.MkdirAll(filepath.Dir(index), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runCharts(&options{chartDirs: stringList{web, db}, indexPath: index}); err != nil {
+		t.Fatalf("runCharts: %v", err)
+	}
+	got, err := os.ReadFile(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Charts
+
+| Chart                                     | Parameters |
+| ----------------------------------------- | ---------- |
+| [web](../charts/web/README.md#parameters) | 2          |
+| db                                        | 1          |
+`
+	if string(got) != want {
+		t.Errorf("index:\n%s\nwant:\n%s", got, want)
+	}
+	if readme, _ := os.ReadFile(filepath.Join(web, "README.md")); !containsRow(string(readme), "replicas") {
+		t.Errorf("README of web was not updated:\n%s", readme)
+	}
+}
+
+func TestRunChartsSkipsIndexOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	ok := writeChart(t, dir, "ok", "## @section S\n## @param a A\na: 1\n", "## Parameters\n")
+	broken := writeChart(t, dir, "broken", "## @section S\na: 1\n", "## Parameters\n")
+	index := filepath.Join(dir, "index.md")
+
+	if err := runCharts(&options{chartDirs: stringList{ok, broken}, indexPath: index}); err == nil {
+		t.Fatal("runCharts succeeded with undocumented keys")
+	}
+	if _, err := os.Stat(index); !os.IsNotExist(err) {
+		t.Errorf("index written although a chart failed: %v", err)
+	}
+}
+
+func TestDebugTrace(t *testing.T) {
+	const values = "## @section Main\n## @param a A\na: 1\n## @skip b\nb: 2\n"
+	tests := []struct {
+		debug bool
+		lines []string
+	}{
+		{debug: false},
+		{debug: true, lines: []string{
+			`DEBUG: line 1: section "Main" (level 0)`,
+			`DEBUG: line 2: param a modifiers=[] section="Main"`,
+			"DEBUG: line 4: skip b",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.debug), func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"values.yaml": values})
+			log := captureLog(t)
+			err := runReadmeGenerator(&options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")},
+				schemaPath: filepath.Join(dir, "values.schema.json"), debug: tt.debug})
+			if err != nil {
+				t.Fatalf("runReadmeGenerator: %v", err)
+			}
+			if !tt.debug && strings.Contains(log.String(), "DEBUG:") {
+				t.Errorf("DEBUG lines without --debug:\n%s", log)
+			}
+			for _, line := range tt.lines {
+				if !strings.Contains(log.String(), line) {
+					t.Errorf("log has no %q:\n%s", line, log)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyChartDir(t *testing.T) {
+	full := writeFiles(t, map[string]string{"Chart.yaml": "name: full\n", "values.yaml": "", "README.md": "", "values.schema.json": "{}"})
+	bare := writeFiles(t, map[string]string{"Chart.yaml": "name: bare\n", "values.yaml": ""})
+	notChart := writeFiles(t, map[string]string{"values.yaml": ""})
+	tests := []struct {
+		name   string
+		opts   options
+		values string
+		readme string
+		schema string
+		err    string
+	}{
+		{
+			name:   "existing files",
+			opts:   options{chartDir: full},
+			values: filepath.Join(full, "values.yaml"),
+			readme: filepath.Join(full, "README.md"),
+			schema: filepath.Join(full, "values.schema.json"),
+		},
+		{
+			name:   "values only",
+			opts:   options{chartDir: bare},
+			values: filepath.Join(bare, "values.yaml"),
+		},
+		{
+			name:   "explicit paths win",
+			opts:   options{chartDir: full, valuesPaths: stringList{"other.yaml"}, readmePath: "DOC.md"},
+			values: "other.yaml",
+			readme: "DOC.md",
+			schema: filepath.Join(full, "values.schema.json"),
+		},
+		{
+			name: "not a chart",
+			opts: options{chartDir: notChart},
+			err:  "is not a Helm chart",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			err := applyChartDir(&opts)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyChartDir: %v", err)
+			}
+			if opts.valuesPaths.String() != tt.values || opts.readmePath != tt.readme || opts.schemaPath != tt.schema {
+				t.Errorf("values %q, readme %q, schema %q; want %q, %q, %q", opts.valuesPaths.String(),
+					opts.readmePath, opts.schemaPath, tt.values, tt.readme, tt.schema)
+			}
+		})
+	}
+}
+
+func TestPostFormat(t *testing.T) {
+	if _, err := exec.LookPath("sed"); err != nil {
+		t.Skip("sed not found")
+	}
+	tests := []struct {
+		name    string
+		command string
+		readme  string
+		err     string
+	}{
+		{name: "none", readme: "| `a`  | A           | `1`   |"},
+		{name: "rewrites the file", command: "sed -i s/A/Z/g", readme: "| `a`  | Z           | `1`   |"},
+		{name: "failing command", command: "false", err: `post-format "false" on `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{
+				"values.yaml": "## @section S\n## @param a A\na: 1\n",
+				"README.md":   "## Parameters\n",
+			})
+			captureLog(t)
+			readme := filepath.Join(dir, "README.md")
+			err := runReadmeGenerator(&options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")},
+				readmePath: readme, postFormat: tt.command})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runReadmeGenerator: %v", err)
+			}
+			if got, _ := os.ReadFile(readme); !strings.Contains(string(got), tt.readme) {
+				t.Errorf("README has no %q:\n%s", tt.readme, got)
+			}
+		})
+	}
+}
+
+func TestKeepGoing(t *testing.T) {
+	const values = "## @section S\n## @param a [default:] A\na: 1\nb: 2\n"
+	tests := []struct {
+		keepGoing bool
+		errs      []string
+		notErrs   []string
+	}{
+		{keepGoing: false, errs: []string{"metadata errors found"}, notErrs: []string{`needs a value`}},
+		{keepGoing: true, errs: []string{"metadata errors found", `a: modifier "default" needs a value`}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.keepGoing), func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"values.yaml": values})
+			captureLog(t)
+			schema := filepath.Join(dir, "values.schema.json")
+			err := runReadmeGenerator(&options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")},
+				schemaPath: schema, keepGoing: tt.keepGoing})
+			if err == nil {
+				t.Fatal("runReadmeGenerator succeeded")
+			}
+			for _, want := range tt.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+			for _, unwanted := range tt.notErrs {
+				if strings.Contains(err.Error(), unwanted) {
+					t.Errorf("error %q contains %q", err, unwanted)
+				}
+			}
+			if _, err := os.Stat(schema); !os.IsNotExist(err) {
+				t.Errorf("schema written despite errors")
+			}
+		})
+	}
+}
+
+func TestFromSchema(t *testing.T) {
+	const schema = `{
+  "title": "Settings",
+  "type": "object",
+  "properties": {
+    "replicas": {"type": "integer", "description": "Replicas", "default": 2},
+    "image": {
+      "type": "object",
+      "properties": {"tag": {"type": "string", "description": "Tag", "default": "1.0"}}
+    }
+  }
+}`
+	tests := []struct {
+		name   string
+		values string
+		rows   []string
+		log    string
+	}{
+		{
+			name: "schema only",
+			rows: []string{"### Settings", "| `image.tag` | Tag         | `1.0` |", "| `replicas`  | Replicas    | `2`   |"},
+		},
+		{
+			name:   "matching values",
+			values: "replicas: 2\nimage:\n  tag: \"1.0\"\n",
+			rows:   []string{"| `replicas`  | Replicas    | `2`   |"},
+		},
+		{
+			name:   "undocumented value",
+			values: "replicas: 2\nimage:\n  tag: \"1.0\"\n  pullPolicy: Always\n",
+			log:    "Missing metadata for key: image.pullPolicy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{
+				"values.schema.json": schema,
+				"values.yaml":        tt.values,
+				"README.md":          "## Parameters\n",
+			})
+			log := captureLog(t)
+			opts := &options{fromSchema: filepath.Join(dir, "values.schema.json"), readmePath: filepath.Join(dir, "README.md")}
+			if tt.values != "" {
+				opts.valuesPaths = stringList{filepath.Join(dir, "values.yaml")}
+			}
+			err := runReadmeGenerator(opts)
+			if tt.log != "" {
+				if err == nil || !strings.Contains(log.String(), tt.log) {
+					t.Fatalf("error = %v, log %q; want %q", err, log.String(), tt.log)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runReadmeGenerator: %v", err)
+			}
+			got, err := os.ReadFile(opts.readmePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range tt.rows {
+				if !strings.Contains(string(got), row) {
+					t.Errorf("README does not contain %q:\n%s", row, got)
+				}
+			}
+		})
+	}
+}
+
+func TestStrict(t *testing.T) {
+	tests := []struct {
+		name   string
+		strict bool
+		err    string
+	}{
+		{name: "warning"},
+		{name: "strict", strict: true, err: "1 warning(s) treated as errors (--strict)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"values.yaml": "## @skip a\na: 1\n"})
+			log := captureLog(t)
+			schema := filepath.Join(dir, "values.schema.json")
+			err := runReadmeGenerator(&options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")},
+				schemaPath: schema, strict: tt.strict})
+			if !strings.Contains(log.String(), "generated schema has no properties") {
+				t.Errorf("log %q does not report the empty schema", log.String())
+			}
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runReadmeGenerator: %v", err)
+			}
+		})
+	}
+}
+
+func TestParamsJSONOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		names  []string
+	}{
+		{
+			name:   "file order",
+			values: "## @section S\n## @param zeta Z\nzeta: 1\n## @param alpha A\nalpha: 2\n## @param mid M\nmid: 3\n",
+			names:  []string{"zeta", "alpha", "mid"},
+		},
+		{
+			name:   "metadata above values",
+			values: "## @section S\n## @param b B\n## @param a A\na: 1\nb: 2\n",
+			names:  []string{"b", "a"},
+		},
+		{
+			name:   "across sections",
+			values: "## @section One\n## @param x X\nx: 1\n## @section Two\n## @param c C\nc: 2\n## @param b B\nb: 3\n",
+			names:  []string{"x", "c", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"values.yaml": tt.values})
+			captureLog(t)
+			out := filepath.Join(dir, "params.json")
+			if err := runReadmeGenerator(&options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")}, paramsJSONPath: out}); err != nil {
+				t.Fatalf("runReadmeGenerator: %v", err)
+			}
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var params []struct {
+				Name  string `json:"name"`
+				Order int    `json:"order"`
+			}
+			if err := json.Unmarshal(data, &params); err != nil {
+				t.Fatal(err)
+			}
+			sort.SliceStable(params, func(i, j int) bool { return params[i].Order < params[j].Order })
+			var names []string
+			for i, p := range params {
+				if i > 0 && p.Order == params[i-1].Order {
+					t.Errorf("%s and %s share order %d", params[i-1].Name, p.Name, p.Order)
+				}
+				names = append(names, p.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.names, ",") {
+				t.Errorf("names by order %v, want %v", names, tt.names)
+			}
+		})
+	}
+}
+
+func TestLintValues(t *testing.T) {
+	const schema = `{
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "replicas": {"type": "integer", "minimum": 1},
+    "image": {
+      "type": "object",
+      "properties": {
+        "tag": {"type": "string", "pattern": "^[0-9.]+$"},
+        "pullPolicy": {"enum": ["Always", "IfNotPresent"]}
+      }
+    },
+    "hosts": {"type": "array", "items": {"type": "string"}},
+    "port": {"$ref": "#/definitions/port"}
+  },
+  "definitions": {"port": {"type": "integer", "maximum": 65535}}
+}`
+	tests := []struct {
+		name       string
+		values     string
+		violations []string
+	}{
+		{
+			name:   "conforming",
+			values: "replicas: 2\nimage:\n  tag: \"1.25\"\n  pullPolicy: Always\nhosts: [a.example.com]\nport: 8080\n",
+		},
+		{
+			name:   "violating",
+			values: "replicas: 0\nimage:\n  tag: latest\n  pullPolicy: Never\nhosts: [a.example.com, 42]\nport: 70000\n",
+			violations: []string{
+				"replicas: 0 is less than the minimum 1",
+				`image.tag: "latest" does not match ^[0-9.]+$`,
+				`image.pullPolicy: value must be one of ["Always","IfNotPresent"]`,
+				"hosts[1]: expected string, got integer",
+				"port: 70000 is greater than the maximum 65535",
+			},
+		},
+		{
+			name:       "missing required",
+			values:     "replicas: 1\n",
+			violations: []string{`(root): missing required key "image"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"values.yaml": tt.values, "values.schema.json": schema})
+			log := captureLog(t)
+			err := runReadmeGenerator(&options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")},
+				lintValues: filepath.Join(dir, "values.schema.json")})
+			if len(tt.violations) == 0 {
+				if err != nil {
+					t.Fatalf("runReadmeGenerator: %v; log %q", err, log.String())
+				}
+				return
+			}
+			if want := fmt.Sprintf("%d value(s) do not match", len(tt.violations)); err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("error = %v, want %q", err, want)
+			}
+			for _, v := range tt.violations {
+				if !strings.Contains(log.String(), "ERROR: "+v+"\n") {
+					t.Errorf("log %q does not report %q", log.String(), v)
+				}
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "changed line",
+			a:    "a\nb\nc\n",
+			b:    "a\nB\nc\n",
+			want: "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "context is limited",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:    "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: "@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "new file",
+			b:    "a\nb\n",
+			want: "@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "added final newline",
+			a:    "a\nb",
+			b:    "a\nb\n",
+			want: "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := "--- README.md\n+++ README.md (generated)\n" + tt.want
+			if got := unifiedDiff("README.md", tt.a, tt.b); got != want {
+				t.Errorf("diff:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	const values = "## @section S\n## @param a A\na: 1\n"
+	current, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Schema: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		readme string
+		schema string
+		err    string
+	}{
+		{name: "up to date", readme: current.Readme, schema: string(current.Schema)},
+		{name: "stale README", readme: readmeHeading, schema: string(current.Schema), err: "1 file(s) would change"},
+		{name: "both stale", readme: readmeHeading, schema: "{}", err: "2 file(s) would change"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"values.yaml": values, "README.md": tt.readme, "values.schema.json": tt.schema})
+			captureLog(t)
+			readme, schema := filepath.Join(dir, "README.md"), filepath.Join(dir, "values.schema.json")
+			err := runReadmeGenerator(&options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")},
+				readmePath: readme, schemaPath: schema, dryRun: true})
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("runReadmeGenerator: %v", err)
+				}
+			} else if !errors.Is(err, ErrDrift) || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want ErrDrift: %s", err, tt.err)
+			}
+			if got, _ := os.ReadFile(readme); string(got) != tt.readme {
+				t.Errorf("the dry run wrote the README:\n%s", got)
+			}
+			if got, _ := os.ReadFile(schema); string(got) != tt.schema {
+				t.Errorf("the dry run wrote the schema:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestModifierReport(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		want   string
+	}{
+		{
+			name: "several modifiers",
+			values: `## @param a [array] A
+## @param b [array,nullable] B
+## @param c [default:x] C
+## @param d [nullabel] D
+## @param e E
+## @skip f
+a: []
+b: []
+c: ""
+d: 1
+e: 1
+f: 1
+`,
+			want: "Modifier usage (4 of 5 parameters use modifiers):\n" +
+				"  array       2\n" +
+				"  default     1\n" +
+				"  nullabel    1  (not a configured modifier)\n" +
+				"  nullable    1\n",
+		},
+		{
+			name:   "repeated on one parameter",
+			values: "## @param a [default:x,default:y] A\na: 1\n",
+			want:   "Modifier usage (1 of 1 parameters use modifiers):\n  default    1\n",
+		},
+		{
+			name:   "none",
+			values: "## @param a A\na: 1\n",
+			want:   "Modifier usage (0 of 1 parameters use modifiers):\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Validation.AllowUnknownModifiers = true
+			meta, err := getParsedMetadata([]valuesFile{{"values.yaml", []byte(tt.values)}}, nil, cfg)
+			if err != nil {
+				t.Fatalf("getParsedMetadata: %v", err)
+			}
+			var out strings.Builder
+			printModifierReport(&out, meta.Parameters, cfg)
+			if out.String() != tt.want {
+				t.Errorf("report:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMultipleValuesFiles(t *testing.T) {
+	const base = "## @section Values\n## @param image.tag Tag\n## @param image.pullPolicy Pull policy\nimage:\n  tag: \"1.0\"\n  pullPolicy: Always\n## @param hosts[0] First host\nhosts: [a]\n"
+	tests := []struct {
+		name       string
+		arrayMerge string
+		extra      string
+		rows       []string
+	}{
+		{
+			name:  "replace",
+			extra: "image:\n  tag: \"2.0\"\nhosts: [b]\n## @param extra Only in the second file\nextra: 1\n",
+			rows: []string{
+				"| `image.tag`        | Tag                     | `2.0`    |",
+				"| `image.pullPolicy` | Pull policy             | `Always` |",
+				"| `hosts[0]`         | First host              | `b`      |",
+				"| `extra`            | Only in the second file | `1`      |",
+			},
+		},
+		{
+			name:       "append",
+			arrayMerge: arrayMergeAppend,
+			extra:      "## @param hosts[1] Second host\nhosts: [b]\n",
+			rows: []string{
+				"| `hosts[0]`         | First host  | `a`      |",
+				"| `hosts[1]`         | Second host | `b`      |",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := "{}"
+			if tt.arrayMerge != "" {
+				cfg = `{"arrayMerge": "` + tt.arrayMerge + `"}`
+			}
+			dir := writeFiles(t, map[string]string{"values.yaml": base, "values-extra.yaml": tt.extra,
+				"README.md": readmeHeading, "config.json": cfg})
+			log := captureLog(t)
+			readme := filepath.Join(dir, "README.md")
+			err := runReadmeGenerator(&options{
+				valuesPaths: stringList{filepath.Join(dir, "values.yaml"), filepath.Join(dir, "values-extra.yaml")},
+				configPaths: stringList{filepath.Join(dir, "config.json")},
+				readmePath:  readme,
+			})
+			if err != nil {
+				t.Fatalf("runReadmeGenerator: %v; log %q", err, log.String())
+			}
+			data, err := os.ReadFile(readme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range tt.rows {
+				if !strings.Contains(string(data), row+"\n") {
+					t.Errorf("README has no row %q:\n%s", row, data)
+				}
+			}
+		})
+	}
+}
+
+func TestFileMode(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+		// existing is the mode of the README before the run.
+		existing os.FileMode
+		want     os.FileMode
+	}{
+		{name: "existing file keeps its mode", existing: 0o600, want: 0o600},
+		{name: "mode set", mode: "0660", existing: 0o600, want: 0o660},
+		{name: "mode without leading zero", mode: "640", existing: 0o644, want: 0o640},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"values.yaml": "## @param a A\na: 1\n", "README.md": readmeHeading})
+			readme := filepath.Join(dir, "README.md")
+			if err := os.Chmod(readme, tt.existing); err != nil {
+				t.Fatal(err)
+			}
+			opts := &options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")}, readmePath: readme,
+				schemaPath: filepath.Join(dir, "values.schema.json")}
+			if tt.mode != "" {
+				if err := opts.fileMode.Set(tt.mode); err != nil {
+					t.Fatalf("Set(%q): %v", tt.mode, err)
+				}
+			}
+			captureLog(t)
+			if err := runReadmeGenerator(opts); err != nil {
+				t.Fatalf("runReadmeGenerator: %v", err)
+			}
+			info, err := os.Stat(readme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != tt.want {
+				t.Errorf("README mode %#o, want %#o", got, tt.want)
+			}
+			if tt.mode == "" {
+				return
+			}
+			if info, err = os.Stat(opts.schemaPath); err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != tt.want {
+				t.Errorf("schema mode %#o, want %#o", got, tt.want)
+			}
+		})
+	}
+
+	for _, mode := range []string{"0", "888", "1000", "rw-r--r--"} {
+		t.Run("invalid "+mode, func(t *testing.T) {
+			var m octalMode
+			if err := m.Set(mode); err == nil || !strings.Contains(err.Error(), "invalid file mode") {
+				t.Errorf("Set(%q) = %v, want an invalid file mode error", mode, err)
+			}
+		})
+	}
+}
+
+func TestOutline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		want   string
+	}{
+		{
+			name:   "nested keys",
+			values: "## @param image.registry Registry\n## @param image.tags[0] Tag\nimage:\n  registry: docker.io\n  tags: [latest]\n## @param resources [object] Resources\nresources: {}\n",
+			want:   "image\n  registry (string)\n  tags\n    [0] (string)\nresources (object)\n",
+		},
+		{
+			name:   "documentation order",
+			values: "## @param b.y Y\n## @param a A\n## @param b.x X\na: 1\nb:\n  x: 1\n  y: 2\n",
+			want:   "b\n  y (integer)\n  x (integer)\na (integer)\n",
+		},
+		{
+			name:   "modifier type",
+			values: "## @param port [string] Port\nport: 80\n",
+			want:   "port (string)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			meta, err := getParsedMetadata([]valuesFile{{"values.yaml", []byte(tt.values)}}, nil, cfg)
+			if err != nil {
+				t.Fatalf("getParsedMetadata: %v", err)
+			}
+			params, err := buildParamsToRender(cloneParameters(meta.Parameters), cfg)
+			if err != nil {
+				t.Fatalf("buildParamsToRender: %v", err)
+			}
+			var out strings.Builder
+			printOutline(&out, params)
+			if out.String() != tt.want {
+				t.Errorf("outline:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSubchartSchema(t *testing.T) {
+	const sub = `{"$schema": "http://json-schema.org/draft-07/schema#", "type": "object",
+		"properties": {"auth": {"$ref": "#/definitions/auth"}}, "definitions": {"auth": {"type": "object"}}}`
+	tests := []struct {
+		name   string
+		values string
+		specs  []string
+		want   string
+		err    string
+	}{
+		{
+			name:   "reference",
+			values: "## @param replicas Replicas\nreplicas: 1\n",
+			specs:  []string{"postgresql=sub.json"},
+			want:   `{"$ref":"#/$defs/postgresql"}`,
+		},
+		{
+			name:   "umbrella keys",
+			values: "## @param postgresql.enabled Enable\npostgresql:\n  enabled: true\n",
+			specs:  []string{"postgresql=sub.json"},
+			want: `{"allOf":[{"$ref":"#/$defs/postgresql"},{"type":"object",` +
+				`"properties":{"enabled":{"default":true,"description":"Enable","type":"boolean"}}}]}`,
+		},
+		{name: "no name", values: "## @param a A\na: 1\n", specs: []string{"=sub.json"}, err: `invalid --subchart-schema "=`},
+		{name: "twice", values: "## @param a A\na: 1\n", specs: []string{"pg=sub.json", "pg=sub.json"}, err: `subchart "pg" is given twice`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"values.yaml": tt.values, "sub.json": sub})
+			var specs stringList
+			for _, s := range tt.specs {
+				name, file, _ := strings.Cut(s, "=")
+				specs = append(specs, name+"="+filepath.Join(dir, file))
+			}
+			log := captureLog(t)
+			schemaPath := filepath.Join(dir, "values.schema.json")
+			err := runReadmeGenerator(&options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")},
+				schemaPath: schemaPath, subchartSchemas: specs})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error()+log.String(), tt.err) {
+					t.Fatalf("error %v, log %q; want %q", err, log.String(), tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runReadmeGenerator: %v; log %q", err, log.String())
+			}
+			data, err := os.ReadFile(schemaPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var schema struct {
+				Properties map[string]interface{}
+				Defs       map[string]map[string]interface{} `json:"$defs"`
+			}
+			if err := json.Unmarshal(data, &schema); err != nil {
+				t.Fatal(err)
+			}
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !jsonEqual(schema.Properties["postgresql"], want) {
+				t.Errorf("postgresql = %v, want %v", schema.Properties["postgresql"], want)
+			}
+			def := schema.Defs["postgresql"]
+			if _, ok := def["$schema"]; ok {
+				t.Errorf("the definition keeps $schema: %v", def)
+			}
+			auth := def["properties"].(map[string]interface{})["auth"]
+			if !jsonEqual(auth, map[string]interface{}{"$ref": "#/$defs/postgresql/definitions/auth"}) {
+				t.Errorf("auth = %v, want a rebased reference", auth)
+			}
+		})
+	}
+}
+
+func TestOutput(t *testing.T) {
+	const values = "## @section S\n## @param a A\na: 1\n"
+	want, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		output string
+		dryRun bool
+		err    string
+	}{
+		{name: "file", output: "docs/README.md"},
+		{name: "stdout", output: "-"},
+		{name: "stdout with dry run", output: "-", dryRun: true, err: "--output - cannot be combined with --dry-run or --post-format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"values.yaml": values, "README.md.tmpl": readmeHeading, "docs/.keep": ""})
+			captureLog(t)
+			opts := &options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")},
+				readmePath: filepath.Join(dir, "README.md.tmpl"), outputPath: tt.output, dryRun: tt.dryRun}
+			out := filepath.Join(dir, "stdout")
+			if tt.output != "-" {
+				opts.outputPath = filepath.Join(dir, tt.output)
+				out = opts.outputPath
+			} else {
+				f, err := os.Create(out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				saved := os.Stdout
+				os.Stdout = f
+				t.Cleanup(func() { os.Stdout = saved; f.Close() })
+			}
+			err := checkOptions(opts)
+			if err == nil {
+				err = runReadmeGenerator(opts)
+			}
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runReadmeGenerator: %v", err)
+			}
+			if got, _ := os.ReadFile(out); string(got) != want.Readme {
+				t.Errorf("output:\n%s\nwant:\n%s", got, want.Readme)
+			}
+			if got, _ := os.ReadFile(opts.readmePath); string(got) != readmeHeading {
+				t.Errorf("the template was rewritten:\n%s", got)
+			}
+		})
+	}
+
+	if err := checkOptions(&options{outputPath: "out.md", valuesPaths: stringList{"values.yaml"}}); err == nil ||
+		err.Error() != "--output requires --readme" {
+		t.Errorf("error %v, want --output requires --readme", err)
+	}
+}
+
+func TestKeyCheck(t *testing.T) {
+	const orphan = "## @section S\n## @param a A\na: 1\n## @param gone Removed key\n"
+	const missing = "## @section S\n## @param a A\na: 1\nextra: 2\n"
+	tests := []struct {
+		name    string
+		values  string
+		strict  bool
+		noFail  bool
+		err     string
+		log     string
+		written bool
+	}{
+		{name: "orphan", values: orphan, log: "values.yaml:4: WARNING: Metadata provided for non existing key: gone", written: true},
+		{name: "orphan strict", values: orphan, strict: true, err: "treated as errors (--strict)"},
+		{name: "missing", values: missing, err: "metadata errors found", log: "values.yaml:4: ERROR: Missing metadata for key: extra"},
+		{name: "missing no-fail", values: missing, noFail: true, log: "values.yaml:4: WARNING: Missing metadata for key: extra", written: true},
+		{name: "no-fail with strict", values: orphan, strict: true, noFail: true, err: "--no-fail cannot be combined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"values.yaml": tt.values, "README.md": readmeHeading})
+			log := captureLog(t)
+			readme, schema := filepath.Join(dir, "README.md"), filepath.Join(dir, "values.schema.json")
+			err := runReadmeGenerator(&options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")},
+				readmePath: readme, schemaPath: schema, strict: tt.strict, noFail: tt.noFail})
+			if tt.err == "" && err != nil {
+				t.Fatalf("runReadmeGenerator: %v\n%s", err, log)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("error %v, want %q", err, tt.err)
+			}
+			if !strings.Contains(log.String(), tt.log) {
+				t.Errorf("log does not contain %q:\n%s", tt.log, log)
+			}
+			if !tt.written {
+				return
+			}
+			data, err := os.ReadFile(readme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !containsRow(string(data), "a") || containsRow(string(data), "gone") {
+				t.Errorf("README:\n%s", data)
+			}
+			data, err = os.ReadFile(schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(data), `"gone"`) {
+				t.Errorf("schema documents the orphan key:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestExcludeFile(t *testing.T) {
+	const values = "## @section S\n## @param a A\na: 1\nglobal:\n  registry: docker.io\n"
+	tests := []struct {
+		name    string
+		exclude string
+		err     string
+	}{
+		{name: "patterns", exclude: "# injected by the umbrella chart\n\n  global.**  \n"},
+		{name: "comment only", exclude: "# global.**\n", err: "metadata errors found"},
+		{name: "invalid pattern", exclude: "# ok\nglobal.[a\n", err: `exclude.txt:2: invalid pattern "global.[a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"values.yaml": values, "exclude.txt": tt.exclude})
+			captureLog(t)
+			err := runReadmeGenerator(&options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")},
+				schemaPath: filepath.Join(dir, "values.schema.json"), excludePath: filepath.Join(dir, "exclude.txt")})
+			if tt.err == "" && err != nil {
+				t.Fatalf("runReadmeGenerator: %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestPreserveLiteralsFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra string
+		want  string
+	}{
+		{name: "first file", extra: "b: 2\n", want: "`0x1F`"},
+		{name: "overridden", extra: "a: 1_000\nb: 2\n", want: "`1_000`"},
+		{name: "overridden plainly", extra: "a: 7\nb: 2\n", want: "`7`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{
+				"values.yaml":       "## @section S\n## @param a A\na: 0x1F\n",
+				"values-extra.yaml": "## @param b B\n" + tt.extra,
+				"README.md":         readmeHeading,
+			})
+			captureLog(t)
+			readme := filepath.Join(dir, "README.md")
+			err := runReadmeGenerator(&options{valuesPaths: stringList{filepath.Join(dir, "values.yaml"), filepath.Join(dir, "values-extra.yaml")},
+				readmePath: readme, preserveLiterals: true})
+			if err != nil {
+				t.Fatalf("runReadmeGenerator: %v", err)
+			}
+			data, err := os.ReadFile(readme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tableCells(tableRow(t, string(data), "a"))[2]; got != tt.want {
+				t.Errorf("value %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadataFiles(t *testing.T) {
+	files := map[string]string{
+		"values.json":     "{\n  \"image\": {\"tag\": \"1.25\"},\n  \"replicas\": 2\n}\n",
+		"values.toml":     "replicas = 2\n",
+		"image.meta.yaml": "## @section Image\n## @param image.tag Tag\n",
+		"other.meta.yaml": "## @section Other\n## @param replicas Replicas\n",
+		"README.md":       readmeHeading,
+	}
+	tests := []struct {
+		name     string
+		values   string
+		metadata []string
+		schema   string
+		err      string
+		rows     []string
+	}{
+		{name: "sidecars", values: "values.json", metadata: []string{"image.meta.yaml", "other.meta.yaml"}, rows: []string{"image.tag", "replicas"}},
+		{name: "missing metadata", values: "values.json", metadata: []string{"image.meta.yaml"}, err: "metadata errors found"},
+		{name: "with --from-schema", values: "values.json", metadata: []string{"image.meta.yaml"}, schema: "values.schema.json",
+			err: "--metadata cannot be combined with --from-schema"},
+		{name: "toml", values: "values.toml", metadata: []string{"other.meta.yaml"}, err: "values.toml: TOML values files are not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, files)
+			captureLog(t)
+			opts := &options{valuesPaths: stringList{filepath.Join(dir, tt.values)}, readmePath: filepath.Join(dir, "README.md")}
+			for _, m := range tt.metadata {
+				opts.metadataPaths = append(opts.metadataPaths, filepath.Join(dir, m))
+			}
+			if tt.schema != "" {
+				opts.fromSchema = filepath.Join(dir, tt.schema)
+			}
+			err := runReadmeGenerator(opts)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runReadmeGenerator: %v", err)
+			}
+			data, err := os.ReadFile(opts.readmePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range tt.rows {
+				if !containsRow(string(data), row) {
+					t.Errorf("README has no row %s:\n%s", row, data)
+				}
+			}
+		})
+	}
+}
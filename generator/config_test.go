@@ -0,0 +1,86 @@
+package generator
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigLayers(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.json":     `{"typeConflict": "error", "readme": {"escapeHTML": true, "rowsPerTable": 5}}`,
+		"override.json": `{"readme": {"rowsPerTable": 10}}`,
+		"broken.json":   `{"readme": `,
+	})
+	path := func(name string) string { return filepath.Join(dir, name) }
+	tests := []struct {
+		name         string
+		files        []string
+		typeConflict string
+		escapeHTML   bool
+		rowsPerTable int
+		err          string
+	}{
+		{name: "defaults", typeConflict: typeConflictModifierWins},
+		{name: "one file", files: []string{path("base.json")}, typeConflict: typeConflictError, escapeHTML: true, rowsPerTable: 5},
+		{name: "later file wins", files: []string{path("base.json"), path("override.json")},
+			typeConflict: typeConflictError, escapeHTML: true, rowsPerTable: 10},
+		{name: "order matters", files: []string{path("override.json"), path("base.json")},
+			typeConflict: typeConflictError, escapeHTML: true, rowsPerTable: 5},
+		{name: "missing file", files: []string{path("missing.json"), path("override.json")},
+			typeConflict: typeConflictModifierWins, rowsPerTable: 10},
+		{name: "invalid file", files: []string{path("base.json"), path("broken.json")}, err: "broken.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfig(tt.files)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want one naming %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.TypeConflict != tt.typeConflict || cfg.Readme.EscapeHTML != tt.escapeHTML || cfg.Readme.RowsPerTable != tt.rowsPerTable {
+				t.Errorf("typeConflict %q, escapeHTML %v, rowsPerTable %d; want %q, %v, %d", cfg.TypeConflict,
+					cfg.Readme.EscapeHTML, cfg.Readme.RowsPerTable, tt.typeConflict, tt.escapeHTML, tt.rowsPerTable)
+			}
+		})
+	}
+}
+
+func TestJSONCConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "line comment", in: "{\"a\": 1 // note\n}", want: "{\"a\": 1 \n}"},
+		{name: "block comment", in: "{/* one\ntwo */\"a\": 1}", want: "{\n\"a\": 1}"},
+		{name: "trailing commas", in: "{\"a\": [1, 2,], \"b\": 3,\n}", want: "{\"a\": [1, 2], \"b\": 3\n}"},
+		{name: "comma before a comment", in: "{\"a\": 1, // last\n}", want: "{\"a\": 1 \n}"},
+		{name: "inside strings", in: `{"a": "http://x/*y*/", "b": "\"//,}"}`, want: `{"a": "http://x/*y*/", "b": "\"//,}"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(stripJSONC([]byte(tt.in))); got != tt.want {
+				t.Errorf("stripJSONC(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	const config = "{\n  // Use the compact layout.\n  \"readme\": { \"compact\": true, },\n  /* strict */\n}\n"
+	dir := writeFiles(t, map[string]string{"config.jsonc": config, "config.json": config})
+	cfg, err := LoadConfig([]string{filepath.Join(dir, "config.jsonc")})
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if !cfg.Readme.Compact {
+		t.Errorf("readme.compact was not read from the .jsonc file")
+	}
+	if _, err := LoadConfig([]string{filepath.Join(dir, "config.json")}); err == nil {
+		t.Errorf("a .json file with comments was accepted")
+	}
+}
@@ -0,0 +1,5462 @@
+// Package generator is a Go re‑implementation of the Helm README & OpenAPI generator originally written in Node.js.
+// It preserves the same command‑line interface:
+//
+//	-v|--values <values.yaml>
+//	-r|--readme <README.md>
+//	-c|--config <config.json> (repeatable)
+//	-s|--schema <schema.json>
+//	--chart-dir <dir> (repeatable)
+//	--index <index.md>
+//	--version
+//
+// The program parses metadata comments inside the Helm values.yaml, validates them, updates
+// the "## Parameters" section of the README with a Markdown table and optionally generates
+// an OpenAPI v3 schema describing the values.
+//
+// Usage example:
+//
+//	readme-generator -v values.yaml -r README.md -s values.schema.json
+//
+// The implementation tries to follow the structure of the original project while adopting
+// Go idioms. The command itself lives in the module root; this package also exposes the
+// rendering steps to Go programs (see api.go).
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"maps"
+	"math"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+//-------------------------------------------------------------------------
+// Version – reported by --version; the command sets it from its ldflags
+//-------------------------------------------------------------------------
+
+var Version = "dev"
+
+//-------------------------------------------------------------------------
+// Command‑line options
+//-------------------------------------------------------------------------
+
+type options struct {
+	chartDirs   stringList
+	chartDir    string
+	indexPath   string
+	valuesPaths stringList
+	readmePath  string
+	outputPath  string
+	configPaths stringList
+	schemaPath  string
+	version     bool
+	debug       bool
+
+	metadataPaths          stringList
+	summarizeComplexValues bool
+	sectionSummary         bool
+	rowAnchors             bool
+	preserveLiterals       bool
+	paramSort              string
+	schemaID               string
+	schemaRootType         string
+	subchartSchemas        stringList
+	postFormat             string
+	keepGoing              bool
+	rowsPerTable           int
+	maxTableWidth          int
+	compact                bool
+	fromSchema             string
+	strict                 bool
+	checkExtraShadowing    bool
+	checkSectionAnchors    bool
+	checkCommentFormat     bool
+	allowUnknownModifiers  bool
+	noFail                 bool
+	excludePath            string
+	paramsJSONPath         string
+	cachePath              string
+	schemaSections         bool
+	lintValues             string
+	dryRun                 bool
+	modifierReport         bool
+	outline                bool
+	fileMode               octalMode
+
+	// summary, when set, receives what the index of a multi-chart run
+	// lists about the chart.
+	summary *chartSummary
+}
+
+// stringList is a flag.Value collecting every occurrence of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// octalMode is a flag.Value holding permissions given in octal, e.g. 0664.
+// Zero means the flag was not given.
+type octalMode os.FileMode
+
+func (m *octalMode) String() string { return fmt.Sprintf("%#o", uint32(*m)) }
+
+func (m *octalMode) Set(v string) error {
+	n, err := strconv.ParseUint(v, 8, 32)
+	if err != nil || n == 0 || n > 0777 {
+		return fmt.Errorf("invalid file mode %q (expected octal permissions such as 0644)", v)
+	}
+	*m = octalMode(n)
+	return nil
+}
+
+func parseFlags() (*options, error) {
+	opts := &options{}
+	flag.Var(&opts.valuesPaths, "values", "Path to values.yaml file (repeatable, later files override earlier ones)")
+	flag.Var(&opts.valuesPaths, "v", "Path to values.yaml file (shorthand)")
+	flag.Var(&opts.metadataPaths, "metadata", "Read the metadata comments from this file instead of the values files (repeatable), e.g. for values.json")
+	flag.StringVar(&opts.readmePath, "readme", "", "Path to README.md file")
+	flag.StringVar(&opts.readmePath, "r", "", "Path to README.md file (shorthand)")
+	flag.StringVar(&opts.outputPath, "output", "", "Write the README there instead of updating --readme in place; - for stdout")
+	flag.StringVar(&opts.outputPath, "o", "", "Write the README there instead of in place (shorthand)")
+	flag.Var(&opts.configPaths, "config", "Path to config.json file (repeatable, later files override earlier ones)")
+	flag.Var(&opts.configPaths, "c", "Path to config.json file (shorthand)")
+	flag.StringVar(&opts.schemaPath, "schema", "", "Path to OpenAPI schema output file")
+	flag.StringVar(&opts.schemaPath, "s", "", "Path to OpenAPI schema output file (shorthand)")
+	flag.StringVar(&opts.paramsJSONPath, "params-json", "", "Path to write the documented parameters as JSON, with their source order")
+	flag.StringVar(&opts.cachePath, "cache", "", "Path to a cache of rendered README sections, reused for sections that did not change")
+	flag.BoolVar(&opts.schemaSections, "schema-sections", false, "Annotate schema properties with their README section (x-section)")
+	flag.StringVar(&opts.schemaID, "schema-id", "", "URI set as the root $id of the generated schema")
+	flag.StringVar(&opts.schemaRootType, "schema-root-type", "", "Type of the schema root (default: the type of the values.yaml root)")
+	flag.Var(&opts.subchartSchemas, "subchart-schema", "Add a subchart's schema as NAME=FILE under $defs, referenced from the property NAME (repeatable)")
+	flag.Var(&opts.fileMode, "file-mode", "Permissions of the written files, in octal (default: 0644 less the umask for new files)")
+	flag.StringVar(&opts.postFormat, "post-format", "", "Command run on each written file, with its path appended (e.g. \"prettier --write\")")
+	flag.StringVar(&opts.lintValues, "lint-values", "", "Validate values.yaml against an existing values.schema.json")
+	flag.StringVar(&opts.fromSchema, "from-schema", "", "Build the README table from an existing values.schema.json instead of values.yaml comments")
+	flag.Var(&opts.chartDirs, "chart-dir", "Helm chart directory; locates values.yaml, README.md and values.schema.json by convention (repeatable)")
+	flag.StringVar(&opts.indexPath, "index", "", "Write a Markdown index linking the README of every --chart-dir")
+	flag.BoolVar(&opts.version, "version", false, "Show generator version")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "Write nothing; print a diff of the files that would change and exit 2 if any")
+	flag.BoolVar(&opts.dryRun, "d", false, "Dry run (shorthand)")
+	flag.BoolVar(&opts.compact, "compact", false, "Render one flat table with a Section column instead of per-section tables")
+	flag.IntVar(&opts.rowsPerTable, "rows-per-table", 0, "Repeat the table header every N rows within a section")
+	flag.IntVar(&opts.maxTableWidth, "max-table-width", 0, "Warn when a README table row is wider than N characters")
+	flag.BoolVar(&opts.checkExtraShadowing, "check-extra-shadowing", false, "Report @extra parameters whose key exists in values.yaml")
+	flag.BoolVar(&opts.checkSectionAnchors, "check-section-anchors", false, "Report sections whose headings produce the same GitHub anchor")
+	flag.BoolVar(&opts.checkCommentFormat, "check-comment-format", false, "Report metadata tags written with another comment prefix than comments.format")
+	flag.StringVar(&opts.excludePath, "exclude", "", "File of key patterns (one per line, e.g. global.**) to neither validate nor document")
+	flag.BoolVar(&opts.allowUnknownModifiers, "allow-unknown-modifiers", false, "Accept modifiers that are not configured instead of failing")
+	flag.BoolVar(&opts.modifierReport, "modifier-report", false, "Print how many parameters use each modifier")
+	flag.BoolVar(&opts.outline, "outline", false, "Print the documented parameters as an indented tree with their types")
+	flag.BoolVar(&opts.strict, "strict", false, "Treat warnings as errors")
+	flag.BoolVar(&opts.strict, "fail-on-warning", false, "Treat warnings as errors (same as --strict)")
+	flag.BoolVar(&opts.noFail, "no-fail", false, "Report missing metadata as a warning instead of failing")
+	flag.BoolVar(&opts.keepGoing, "keep-going", false, "Report errors from every stage instead of stopping at the first")
+	flag.BoolVar(&opts.debug, "debug", false, "Trace metadata parsing decisions")
+	flag.BoolVar(&opts.summarizeComplexValues, "summarize-complex-values", false, "Summarize object/array values in the README table")
+	flag.BoolVar(&opts.sectionSummary, "section-summary", false, "Show the first sentence of each section description in italics under its heading")
+	flag.BoolVar(&opts.rowAnchors, "row-anchors", false, "Emit an anchor for every parameter row, e.g. #image-tag")
+	flag.BoolVar(&opts.preserveLiterals, "preserve-literals", false, "Show values as written in values.yaml (0x1F, 1_000, \"3.10\") instead of decoded")
+	flag.StringVar(&opts.paramSort, "param-sort", "", "Order of the parameters within each section: file (default) or alpha")
+	flag.Parse()
+
+	if opts.version {
+		return opts, nil
+	}
+
+	if opts.indexPath != "" && len(opts.chartDirs) == 0 {
+		return nil, errors.New("--index requires --chart-dir")
+	}
+	if multiChart(opts) {
+		// Each chart is checked on its own by runCharts.
+		if len(opts.valuesPaths) > 0 || len(opts.metadataPaths) > 0 || opts.readmePath != "" || opts.outputPath != "" ||
+			opts.schemaPath != "" || opts.paramsJSONPath != "" || opts.cachePath != "" || opts.lintValues != "" ||
+			opts.fromSchema != "" || len(opts.subchartSchemas) > 0 {
+			return nil, errors.New("several --chart-dir or --index take the paths of every chart from its directory; " +
+				"--values, --metadata, --readme, --output, --schema, --params-json, --cache, --lint-values, " +
+				"--from-schema and --subchart-schema cannot be given")
+		}
+	} else {
+		if len(opts.chartDirs) == 1 {
+			opts.chartDir = opts.chartDirs[0]
+			if err := applyChartDir(opts); err != nil {
+				return nil, err
+			}
+		}
+		if err := checkOptions(opts); err != nil {
+			return nil, err
+		}
+	}
+
+	// Default config path next to executable
+	if len(opts.configPaths) == 0 {
+		exe, _ := os.Executable()
+		opts.configPaths = stringList{filepath.Join(filepath.Dir(exe), "config.json")}
+	}
+	return opts, nil
+}
+
+// multiChart reports whether the command documents several charts, or one
+// chart with an index.
+func multiChart(opts *options) bool {
+	return len(opts.chartDirs) > 1 || opts.indexPath != ""
+}
+
+// checkOptions rejects combinations of options that cannot work together or
+// leave nothing to do.
+func checkOptions(opts *options) error {
+	if opts.outputPath != "" && opts.readmePath == "" {
+		return errors.New("--output requires --readme")
+	}
+	if opts.outputPath == "-" && (opts.dryRun || opts.postFormat != "") {
+		return errors.New("--output - cannot be combined with --dry-run or --post-format")
+	}
+	if len(opts.subchartSchemas) > 0 && opts.schemaPath == "" {
+		return errors.New("--subchart-schema requires --schema")
+	}
+	if opts.fromSchema != "" {
+		if opts.readmePath == "" {
+			return errors.New("--from-schema requires --readme")
+		}
+		if opts.schemaPath != "" {
+			return errors.New("--from-schema cannot be combined with --schema")
+		}
+	} else if len(opts.valuesPaths) == 0 {
+		return errors.New("--values is required")
+	}
+	// In a multi-chart run a chart without README or schema is still
+	// validated and indexed.
+	if opts.summary == nil && opts.readmePath == "" && opts.schemaPath == "" && opts.paramsJSONPath == "" && opts.lintValues == "" && !opts.modifierReport && !opts.outline {
+		return errors.New("nothing to do – provide --readme, --schema, --params-json, --lint-values, --modifier-report and/or --outline")
+	}
+	return nil
+}
+
+// applyChartDir fills the paths not given explicitly from the standard Helm
+// chart layout. values.yaml is always used; README.md and values.schema.json
+// only when they already exist in the chart.
+func applyChartDir(opts *options) error {
+	if _, err := os.Stat(filepath.Join(opts.chartDir, "Chart.yaml")); err != nil {
+		return fmt.Errorf("%s is not a Helm chart: %w", opts.chartDir, err)
+	}
+	if len(opts.valuesPaths) == 0 {
+		opts.valuesPaths = stringList{filepath.Join(opts.chartDir, "values.yaml")}
+	}
+	if opts.readmePath == "" {
+		if p := filepath.Join(opts.chartDir, "README.md"); fileExists(p) {
+			opts.readmePath = p
+		}
+	}
+	if opts.schemaPath == "" {
+		if p := filepath.Join(opts.chartDir, "values.schema.json"); fileExists(p) {
+			opts.schemaPath = p
+		}
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+//-------------------------------------------------------------------------
+// Logging – INFO/ERROR lines always, DEBUG lines only with --debug
+//-------------------------------------------------------------------------
+
+type logger struct {
+	w        io.Writer
+	debug    bool
+	warnings int
+	once     map[string]bool
+}
+
+// console is the logger of the running command or API call. It discards
+// everything until the command points it at stdout.
+var console = &logger{w: io.Discard}
+
+func (l *logger) Info(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, "INFO: "+format+"\n", args...)
+}
+
+func (l *logger) Warn(format string, args ...interface{}) {
+	l.warnings++
+	fmt.Fprintf(l.w, "WARNING: "+format+"\n", args...)
+}
+
+func (l *logger) Error(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, "ERROR: "+format+"\n", args...)
+}
+
+// WarnAt is Warn prefixed with "file:line: " when the line is known.
+func (l *logger) WarnAt(file string, line int, format string, args ...interface{}) {
+	if line > 0 {
+		fmt.Fprintf(l.w, "%s:%d: ", file, line)
+	}
+	l.Warn(format, args...)
+}
+
+// WarnAtOnce is WarnAt for messages that rendering the README and building
+// the schema both run into: each is only logged the first time.
+func (l *logger) WarnAtOnce(file string, line int, format string, args ...interface{}) {
+	key := fmt.Sprintf("%s:%d: "+format, append([]interface{}{file, line}, args...)...)
+	if l.once[key] {
+		return
+	}
+	if l.once == nil {
+		l.once = map[string]bool{}
+	}
+	l.once[key] = true
+	l.WarnAt(file, line, format, args...)
+}
+
+// ErrorAt is Error prefixed with "file:line: " when the line is known, the
+// form editors jump to.
+func (l *logger) ErrorAt(file string, line int, format string, args ...interface{}) {
+	if line > 0 {
+		fmt.Fprintf(l.w, "%s:%d: ", file, line)
+	}
+	l.Error(format, args...)
+}
+
+func (l *logger) Debug(format string, args ...interface{}) {
+	if l.debug {
+		fmt.Fprintf(l.w, "DEBUG: "+format+"\n", args...)
+	}
+}
+
+//-------------------------------------------------------------------------
+// Data structures (mirrors the JS classes)
+//-------------------------------------------------------------------------
+
+type Parameter struct {
+	Name         string      `json:"name"` // dot‑notation path, e.g. image.repository
+	Description  string      `json:"description"`
+	DisplayName  string      `json:"displayName,omitempty"` // @alias shown in the README instead of Name
+	Value        interface{} `json:"value"`
+	Type         string      `json:"type"`
+	Modifiers    []string    `json:"modifiers,omitempty"`
+	Enum         []string    `json:"enum,omitempty"` // values allowed by an "enum" modifier
+	Section      string      `json:"section,omitempty"`
+	Order        int         `json:"order"` // position of the metadata in the source file
+	Implicit     bool        `json:"-"`     // documented by a plain comment rather than a tag
+	Literal      string      `json:"-"`     // how a coerced value is written in values.yaml
+	File         string      `json:"-"`     // values file of the metadata line, or of the key for values
+	Line         int         `json:"-"`     // line in File; 0 when unknown
+	DisplayValue string      `json:"-"`     // @default text shown instead of the (still validated) value
+	SkipReason   string      `json:"-"`     // text after the key of an @skip, kept as a README comment
+	ExampleLines []string    `json:"-"`     // @example block, rendered as a YAML code block below the table
+	Source       string      `json:"-"`     // scalar as written, shown with readme.preserveLiterals
+
+	Validate bool `json:"-"`
+	Readme   bool `json:"-"`
+	Schema   bool `json:"-"`
+}
+
+func NewParameter(name string) *Parameter {
+	return &Parameter{
+		Name:     name,
+		Validate: true,
+		Readme:   true,
+		Schema:   true,
+	}
+}
+
+// DocName is the name shown in the README: the @alias if any, else the key.
+func (p *Parameter) DocName() string {
+	if p.DisplayName != "" {
+		return p.DisplayName
+	}
+	return p.Name
+}
+
+func (p *Parameter) HasModifier(m string) bool {
+	for _, mm := range p.Modifiers {
+		if mm == m {
+			return true
+		}
+	}
+	return false
+}
+
+// ModifierValue returns the value of a "name:value" modifier.
+func (p *Parameter) ModifierValue(name string) (string, bool) {
+	for _, mm := range p.Modifiers {
+		if strings.HasPrefix(mm, name+":") {
+			return strings.TrimSpace(strings.TrimPrefix(mm, name+":")), true
+		}
+	}
+	return "", false
+}
+
+// HasModifierPrefix reports whether a "name:value" modifier is present.
+func (p *Parameter) HasModifierPrefix(name string) bool {
+	_, ok := p.ModifierValue(name)
+	return ok
+}
+
+// Extra behaves like JS getter/setter pair. Simpler with bool field.
+func (p *Parameter) SetExtra(b bool) {
+	if b {
+		p.Validate = false
+		p.Readme = true
+	}
+}
+
+func (p *Parameter) Extra() bool { return !p.Validate && p.Readme }
+
+func (p *Parameter) SetSkip(b bool) {
+	if b {
+		p.Validate = false
+		p.Readme = false
+	} else {
+		p.Validate = true
+		p.Readme = true
+	}
+}
+
+func (p *Parameter) Skip() bool { return !p.Validate && !p.Readme }
+
+//-------------------------------------------------------------------------
+
+type Section struct {
+	Name             string
+	DescriptionLines []string
+	Parameters       []*Parameter
+	// Level is the nesting depth given by the "#" written after the tag:
+	// "@section # Connection Pool" is one level below the section before it.
+	Level int
+	// Skipped are the @skip parameters of the section that give a reason.
+	Skipped []*Parameter
+}
+
+// Description joins the description lines with "\n", the line ending used for
+// all rendered Markdown; insertReadmeTable converts it to the README's own.
+func (s *Section) Description() string { return strings.Join(s.DescriptionLines, "\n") }
+
+//-------------------------------------------------------------------------
+
+type Metadata struct {
+	Sections   []*Section
+	Parameters []*Parameter
+	// RootType is the schema type of the values file root ("object" for a
+	// map, "array", or a scalar type); empty when unknown.
+	RootType string
+	// Keys lists the keys whose metadata does not match values.yaml.
+	Keys KeyReport
+}
+
+func (m *Metadata) AddSection(sec *Section) { m.Sections = append(m.Sections, sec) }
+
+// AddParameter appends p, recording its position in the source as p.Order.
+func (m *Metadata) AddParameter(p *Parameter) {
+	p.Order = len(m.Parameters)
+	m.Parameters = append(m.Parameters, p)
+}
+
+//-------------------------------------------------------------------------
+// Config JSON
+//-------------------------------------------------------------------------
+
+type Config struct {
+	// TypeConflict decides what happens when a type modifier (array, object,
+	// string) disagrees with the type of the actual value: "modifier-wins"
+	// (default), "value-wins" or "error".
+	TypeConflict string `json:"typeConflict"`
+	// BooleanCoercion decides how "boolean" modifiers treat "true"/"false"
+	// strings and 1/0: "none" (default) applies TypeConflict, "coerce"
+	// documents them as the booleans they stand for.
+	BooleanCoercion string `json:"booleanCoercion"`
+	// ArrayMerge decides how an array in a later values file combines with
+	// the same array in an earlier one: "replace" (default, as Helm does)
+	// or "append".
+	ArrayMerge string `json:"arrayMerge"`
+	// StabilityLevels are the values accepted by the "stability" modifier.
+	StabilityLevels []string `json:"stabilityLevels"`
+	// Exclude lists key patterns, such as "global.**", that are neither
+	// validated nor documented; see matchKeyPattern.
+	Exclude []string `json:"exclude"`
+
+	Comments struct {
+		Format string `json:"format"`
+		// PlainAsDescription documents a key that has no @param with the
+		// plain comment lines directly above it.
+		PlainAsDescription bool `json:"plainAsDescription"`
+		// MultilineDescriptions continues a @param description on the
+		// plain comment lines right below it.
+		MultilineDescriptions bool `json:"multilineDescriptions"`
+	} `json:"comments"`
+	Tags struct {
+		Param            string `json:"param"`
+		Section          string `json:"section"`
+		DescriptionStart string `json:"descriptionStart"`
+		DescriptionEnd   string `json:"descriptionEnd"`
+		Skip             string `json:"skip"`
+		Extra            string `json:"extra"`
+		// Include inlines a file into a section description.
+		Include string `json:"include"`
+		// Default documents the effective default of the preceding @param
+		// as free text.
+		Default string `json:"default"`
+		// ExampleStart and ExampleEnd enclose an example of the preceding
+		// @param, rendered below its section's table.
+		ExampleStart string `json:"exampleStart"`
+		ExampleEnd   string `json:"exampleEnd"`
+		// Alias follows the key of a @param and gives the name shown in
+		// the README, e.g. "@param internal.v2.imageRef @alias image.repository".
+		Alias string `json:"alias"`
+	} `json:"tags"`
+	Regexp struct {
+		ParamsSectionTitle string `json:"paramsSectionTitle"`
+	} `json:"regexp"`
+	Readme struct {
+		// EscapeHTML escapes '<' and '>' in descriptions so raw markup is
+		// shown as text instead of being rendered.
+		EscapeHTML bool `json:"escapeHTML"`
+		// Columns lists the table columns in order, from the column*
+		// names.
+		Columns []string `json:"columns"`
+		// TypeColumn adds the Type column after Name.
+		//
+		// Deprecated: list "Type" in Columns.
+		TypeColumn bool `json:"typeColumn"`
+		// RequiredColumn adds the Required column after Type or Name.
+		//
+		// Deprecated: list "Required" in Columns.
+		RequiredColumn bool `json:"requiredColumn"`
+		// SummarizeComplexValues renders non-empty object/array values as
+		// "{3 keys}" / "[5 items]" and lists them in full below the table.
+		SummarizeComplexValues bool `json:"summarizeComplexValues"`
+		// SectionSummary repeats the first sentence of a section's
+		// description in italics right under its heading.
+		SectionSummary bool `json:"sectionSummary"`
+		// PreserveLiterals shows scalars as written in values.yaml when
+		// decoding changes how they read, e.g. 0x1F or "3.10".
+		PreserveLiterals bool `json:"preserveLiterals"`
+		// ParamSort is one of the paramSort* values and orders the rows of
+		// each section's table.
+		ParamSort string `json:"paramSort"`
+		// RowsPerTable splits a section's table after every N rows,
+		// repeating the header; 0 keeps a single table.
+		RowsPerTable int `json:"rowsPerTable"`
+		// MaxTableWidth warns about tables whose rows are wider than this
+		// many characters; 0 disables the check.
+		MaxTableWidth int `json:"maxTableWidth"`
+		// AnchorPrefix, when set, emits an explicit anchor named
+		// prefix+slug before every section heading, and prefixes the row
+		// anchors of RowAnchors.
+		AnchorPrefix string `json:"anchorPrefix"`
+		// RowAnchors emits an anchor before the name of every parameter,
+		// named by rowAnchor, so that single rows can be linked to.
+		RowAnchors bool `json:"rowAnchors"`
+		// TemplateNote is appended to the description of "template"
+		// parameters.
+		TemplateNote string `json:"templateNote"`
+		// EmptySection is rendered instead of the table for sections
+		// without parameters; empty renders nothing.
+		EmptySection string `json:"emptySection"`
+		// TableStyle is one of the tableStyle* values.
+		TableStyle string `json:"tableStyle"`
+		// DigitSeparator groups the digits of integers with five digits or
+		// more in the Value column, e.g. "," for 10,000; empty disables it.
+		DigitSeparator string `json:"digitSeparator"`
+		// FileDefaultMaxLength truncates "fromFile" defaults in the table
+		// to that many characters; 0 disables truncation.
+		FileDefaultMaxLength int `json:"fileDefaultMaxLength"`
+		// RequiredPlaceholder is shown as the value of required parameters
+		// that are unset (null) in values.yaml.
+		RequiredPlaceholder string `json:"requiredPlaceholder"`
+		// Compact renders a single table for all sections, with a Section
+		// column instead of per-section headings.
+		Compact bool `json:"compact"`
+		// Headers are the labels of the table columns.
+		Headers struct {
+			Section     string `json:"section"`
+			Name        string `json:"name"`
+			Type        string `json:"type"`
+			Required    string `json:"required"`
+			Description string `json:"description"`
+			Value       string `json:"value"`
+		} `json:"headers"`
+	} `json:"readme"`
+	Validation struct {
+		// ExtraShadowing reports @extra parameters whose key is a real
+		// value in values.yaml (and would thus skip its validation).
+		ExtraShadowing bool `json:"extraShadowing"`
+		// SectionAnchors reports sections whose headings slugify to the
+		// same anchor, which makes deep links ambiguous.
+		SectionAnchors bool `json:"sectionAnchors"`
+		// CommentFormat reports tag lines whose comment prefix differs
+		// from Comments.Format (# @param with "##"), which are ignored.
+		CommentFormat bool `json:"commentFormat"`
+		// CollapseAliases treats an alias of a map or list as a single key
+		// to document, instead of a copy of every key below its anchor.
+		CollapseAliases bool `json:"collapseAliases"`
+		// SectionPrefixes maps a section name to the key prefixes its
+		// parameters must start with, e.g. "Ingress": ["ingress"].
+		SectionPrefixes map[string][]string `json:"sectionPrefixes"`
+		// AllowUnknownModifiers accepts modifiers missing from Modifiers,
+		// which are otherwise reported as typos.
+		AllowUnknownModifiers bool `json:"allowUnknownModifiers"`
+		// NoFail reports missing metadata as a warning, like metadata for
+		// keys that do not exist, so the key check never fails the run.
+		NoFail bool `json:"noFail"`
+	} `json:"validation"`
+	Schema struct {
+		// ID is emitted as the root "$id" of the generated schema.
+		ID string `json:"id"`
+		// CapitalizeDescriptions upper-cases the first letter of every
+		// description in the schema.
+		CapitalizeDescriptions bool `json:"capitalizeDescriptions"`
+		// TrailingPeriod is one of the trailingPeriod* policies.
+		TrailingPeriod string `json:"trailingPeriod"`
+		// Dialect is one of the schemaDialect* values and decides how a
+		// type list is expressed.
+		Dialect string `json:"dialect"`
+		// Sections adds "x-section" with the README section name to every
+		// documented property.
+		Sections bool `json:"sections"`
+		// RootType forces the type of the schema root; empty uses the type
+		// of the values file root.
+		RootType string `json:"rootType"`
+		// KubernetesDefinitions is the URL of the Kubernetes definitions
+		// document that "k8s-type" modifiers reference.
+		KubernetesDefinitions string `json:"kubernetesDefinitions"`
+		// Deduplicate moves object schemas repeated with identical content
+		// into "definitions", referenced with "$ref"; see definitionsKey.
+		Deduplicate bool `json:"deduplicate"`
+		// AdditionalProperties false closes every object with documented
+		// keys with "additionalProperties": false.
+		AdditionalProperties bool `json:"additionalProperties"`
+	} `json:"schema"`
+	Modifiers struct {
+		Array    string `json:"array"`
+		Object   string `json:"object"`
+		String   string `json:"string"`
+		Nullable string `json:"nullable"`
+		Default  string `json:"default"`
+		Duration string `json:"duration"`
+		ByteSize string `json:"bytesize"`
+		// DNSLabel and DNSSubdomain mark Kubernetes resource names (RFC 1123
+		// labels and subdomains).
+		DNSLabel     string `json:"dnsLabel"`
+		DNSSubdomain string `json:"dnsSubdomain"`
+		// PropertyNames is used as "propertyNames:<pattern>".
+		PropertyNames string `json:"propertyNames"`
+		// OneOfGroup is used as "oneOf-group:<name>"; at most one member of
+		// a group may be set (non-null).
+		OneOfGroup string `json:"oneOfGroup"`
+		// DefaultRef is used as "default-ref:<key>" for values that
+		// default to another parameter.
+		DefaultRef string `json:"defaultRef"`
+		// FromFile is used as "fromFile:<path>"; the default is the content
+		// of that file, relative to the values file.
+		FromFile string `json:"fromFile"`
+		// IfRequired is used as "if-required:<flag key>"; the parameter is
+		// required whenever that flag is true.
+		IfRequired string `json:"ifRequired"`
+		Required   string `json:"required"`
+		// Percentage marks an integer between 0 and 100.
+		Percentage string `json:"percentage"`
+		// Type is used as "type:string|integer" for values accepting
+		// several types.
+		Type string `json:"type"`
+		// ExampleCode is used as "example-code:<snippet>" and must come
+		// last; the snippet is rendered in a code block below the table.
+		ExampleCode string `json:"exampleCode"`
+		// Template marks string values rendered by Helm's tpl.
+		Template string `json:"template"`
+		// Deprecated is used bare or as "deprecated:removed-in=<version>".
+		Deprecated string `json:"deprecated"`
+		Boolean    string `json:"boolean"`
+		// Stability is used as "stability:<level>", with a level from
+		// StabilityLevels.
+		Stability string `json:"stability"`
+		// K8sType is used as "k8s-type:<definition>", e.g.
+		// "k8s-type:io.k8s.api.core.v1.SecurityContext".
+		K8sType string `json:"k8sType"`
+		// AllowedVersions is used as "allowedVersions:<constraint>", e.g.
+		// "allowedVersions:>=1.2.0" on an image tag. The constraint is
+		// kept as written.
+		AllowedVersions string `json:"allowedVersions"`
+		// Pattern is used as "pattern:<regexp>" for string values.
+		Pattern string `json:"pattern"`
+		// ErrorMessage is used as "errorMessage:<text>"; the text is shown
+		// by form generators when the value fails validation.
+		ErrorMessage string `json:"errorMessage"`
+		// Enum is used as "enum:<v1>,<v2>,..."; the list runs up to the
+		// next configured modifier.
+		Enum string `json:"enum"`
+		// Redact marks values that logging and telemetry tools should
+		// redact at runtime; the README is unchanged.
+		Redact string `json:"redact"`
+		// Password marks string values that form generators should
+		// render as masked inputs.
+		Password string `json:"password"`
+	} `json:"modifiers"`
+	// Patterns holds the regular expressions emitted as schema "pattern"
+	// for the format modifiers.
+	Patterns struct {
+		Duration     string `json:"duration"`
+		ByteSize     string `json:"bytesize"`
+		DNSLabel     string `json:"dnsLabel"`
+		DNSSubdomain string `json:"dnsSubdomain"`
+	} `json:"patterns"`
+}
+
+// DefaultConfig returns the built-in defaults that are used when
+// no explicit config file is present.
+func DefaultConfig() *Config {
+	cfg := &Config{}
+	cfg.TypeConflict = typeConflictModifierWins
+	cfg.BooleanCoercion = booleanCoercionNone
+	cfg.ArrayMerge = arrayMergeReplace
+	cfg.StabilityLevels = []string{"stable", "beta", "alpha"}
+	cfg.Comments.Format = "##"
+
+	cfg.Tags.Param = "@param"
+	cfg.Tags.Section = "@section"
+	cfg.Tags.DescriptionStart = "@descriptionStart"
+	cfg.Tags.DescriptionEnd = "@descriptionEnd"
+	cfg.Tags.Skip = "@skip"
+	cfg.Tags.Extra = "@extra"
+	cfg.Tags.Include = "@include"
+	cfg.Tags.Default = "@default"
+	cfg.Tags.ExampleStart = "@example"
+	cfg.Tags.ExampleEnd = "@exampleEnd"
+	cfg.Tags.Alias = "@alias"
+
+	cfg.Modifiers.Array = "array"
+	cfg.Modifiers.Object = "object"
+	cfg.Modifiers.String = "string"
+	cfg.Modifiers.Nullable = "nullable"
+	cfg.Modifiers.Default = "default"
+	cfg.Modifiers.Duration = "duration"
+	cfg.Modifiers.ByteSize = "bytesize"
+	cfg.Modifiers.DNSLabel = "dns-label"
+	cfg.Modifiers.DNSSubdomain = "dns-subdomain"
+	cfg.Modifiers.PropertyNames = "propertyNames"
+	cfg.Modifiers.OneOfGroup = "oneOf-group"
+	cfg.Modifiers.DefaultRef = "default-ref"
+	cfg.Modifiers.FromFile = "fromFile"
+	cfg.Modifiers.IfRequired = "if-required"
+	cfg.Modifiers.Required = "required"
+	cfg.Modifiers.Percentage = "percentage"
+	cfg.Modifiers.Type = "type"
+	cfg.Modifiers.ExampleCode = "example-code"
+	cfg.Modifiers.Template = "template"
+	cfg.Modifiers.Deprecated = "deprecated"
+	cfg.Modifiers.Boolean = "boolean"
+	cfg.Modifiers.Stability = "stability"
+	cfg.Modifiers.K8sType = "k8s-type"
+	cfg.Modifiers.AllowedVersions = "allowedVersions"
+	cfg.Modifiers.Pattern = "pattern"
+	cfg.Modifiers.ErrorMessage = "errorMessage"
+	cfg.Modifiers.Enum = "enum"
+	cfg.Modifiers.Redact = "redact"
+	cfg.Modifiers.Password = "password"
+
+	cfg.Patterns.Duration = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	cfg.Patterns.ByteSize = `^[0-9]+(\.[0-9]+)?([EPTGMK]i|[EPTGMk])?$`
+	cfg.Patterns.DNSLabel = `^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	cfg.Patterns.DNSSubdomain = `^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+
+	cfg.Regexp.ParamsSectionTitle = "Parameters"
+
+	cfg.Readme.FileDefaultMaxLength = 80
+	cfg.Readme.RequiredPlaceholder = "<must be set>"
+	cfg.Readme.TemplateNote = "Supports templating (`{{ ... }}`)."
+	cfg.Readme.TableStyle = tableStylePadded
+	cfg.Readme.ParamSort = paramSortFile
+	cfg.Readme.Columns = []string{columnName, columnDescription, columnValue}
+	cfg.Readme.Headers.Section = "Section"
+	cfg.Readme.Headers.Name = "Name"
+	cfg.Readme.Headers.Type = "Type"
+	cfg.Readme.Headers.Required = "Required"
+	cfg.Readme.Headers.Description = "Description"
+	cfg.Readme.Headers.Value = "Value"
+
+	cfg.Schema.TrailingPeriod = trailingPeriodKeep
+	cfg.Schema.Dialect = schemaDialectOpenAPI
+	cfg.Schema.KubernetesDefinitions = "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/master/_definitions.json"
+	cfg.Schema.Deduplicate = true
+	cfg.Schema.AdditionalProperties = true
+	return cfg
+}
+
+// LoadConfig layers the given config files over the built-in defaults in
+// order: each file only overrides the keys it sets, so later files win.
+// Missing files are ignored. Files named *.jsonc may contain comments and
+// trailing commas.
+func LoadConfig(paths []string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if strings.EqualFold(filepath.Ext(path), ".jsonc") {
+			data = stripJSONC(data)
+		}
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	foldDeprecatedColumns(cfg)
+	if err := validateConfig(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// foldDeprecatedColumns moves readme.typeColumn and readme.requiredColumn into
+// readme.columns, after Name, and warns that they are deprecated. Both are
+// cleared, so readme.columns is the only setting read when rendering.
+func foldDeprecatedColumns(cfg *Config) {
+	cols := cfg.Readme.Columns
+	after := slices.Index(cols, columnName) + 1
+	if cfg.Readme.TypeColumn {
+		console.Warn(`readme.typeColumn is deprecated; list "Type" in readme.columns instead`)
+		if !slices.Contains(cols, columnType) {
+			cols = slices.Insert(slices.Clone(cols), after, columnType)
+		}
+	}
+	if i := slices.Index(cols, columnType); i >= 0 {
+		after = i + 1
+	}
+	if cfg.Readme.RequiredColumn {
+		console.Warn(`readme.requiredColumn is deprecated; list "Required" in readme.columns instead`)
+		if !slices.Contains(cols, columnRequired) {
+			cols = slices.Insert(slices.Clone(cols), after, columnRequired)
+		}
+	}
+	cfg.Readme.Columns = cols
+	cfg.Readme.TypeColumn = false
+	cfg.Readme.RequiredColumn = false
+}
+
+// stripJSONC turns JSON with comments into plain JSON: "//" and "/* */"
+// comments and commas right before a closing bracket are dropped, outside
+// of strings. Line breaks of comments are kept, so lines do not shift.
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	// comma is the position in out of a comma that may turn out trailing.
+	comma := -1
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '"':
+			j := i + 1
+			for ; j < len(data) && data[j] != '"'; j++ {
+				if data[j] == '\\' {
+					j++
+				}
+			}
+			out = append(out, data[i:min(j+1, len(data))]...)
+			i = j
+			comma = -1
+			continue
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			i--
+			continue
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				end = len(data) - i - 2
+			}
+			out = append(out, bytes.Repeat([]byte("\n"), bytes.Count(data[i:i+2+end], []byte("\n")))...)
+			i += end + 3
+			continue
+		case c == ',':
+			comma = len(out)
+		case c == '}' || c == ']':
+			if comma >= 0 {
+				out = append(out[:comma], out[comma+1:]...)
+			}
+			comma = -1
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			comma = -1
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// Type conflict policies accepted by Config.TypeConflict.
+const (
+	typeConflictModifierWins = "modifier-wins"
+	typeConflictValueWins    = "value-wins"
+	typeConflictError        = "error"
+)
+
+// Array merge policies accepted by Config.ArrayMerge.
+const (
+	arrayMergeReplace = "replace"
+	arrayMergeAppend  = "append"
+)
+
+// Boolean coercion policies accepted by Config.BooleanCoercion.
+const (
+	booleanCoercionNone   = "none"
+	booleanCoercionCoerce = "coerce"
+)
+
+// Trailing period policies accepted by Config.Schema.TrailingPeriod.
+const (
+	trailingPeriodKeep  = "keep"
+	trailingPeriodStrip = "strip"
+	trailingPeriodAdd   = "add"
+)
+
+// Table styles accepted by Config.Readme.TableStyle. Compact tables are not
+// aligned, so changing one cell does not re-pad the whole column.
+const (
+	tableStylePadded  = "padded"
+	tableStyleCompact = "compact"
+)
+
+// Parameter orders accepted by Config.Readme.ParamSort. Sorting only
+// reorders the rows within a section; sections keep their file order.
+const (
+	paramSortFile  = "file"
+	paramSortAlpha = "alpha"
+)
+
+// Column names accepted in Config.Readme.Columns. The labels shown in the
+// header come from Config.Readme.Headers.
+const (
+	columnName        = "Name"
+	columnType        = "Type"
+	columnRequired    = "Required"
+	columnDescription = "Description"
+	columnValue       = "Value"
+
+	// columnSection is added by readme.compact, not listed in columns.
+	columnSection = "Section"
+)
+
+// Schema dialects accepted by Config.Schema.Dialect. OpenAPI 3.0 has no type
+// arrays, so type lists become a oneOf there.
+const (
+	schemaDialectOpenAPI = "openapi-3.0"
+	schemaDialectDraft07 = "draft-07"
+)
+
+// Length limits of Kubernetes resource names, emitted as schema maxLength
+// with the dns-label and dns-subdomain patterns.
+const (
+	dnsLabelMaxLength     = 63
+	dnsSubdomainMaxLength = 253
+)
+
+// schemaTypes are the type names accepted in a "type:" modifier.
+var schemaTypes = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true, "object": true, "array": true, "null": true,
+}
+
+func validateConfig(cfg *Config) error {
+	switch cfg.TypeConflict {
+	case typeConflictModifierWins, typeConflictValueWins, typeConflictError:
+	default:
+		return fmt.Errorf("invalid typeConflict %q (expected %s, %s or %s)", cfg.TypeConflict,
+			typeConflictModifierWins, typeConflictValueWins, typeConflictError)
+	}
+	switch cfg.Schema.TrailingPeriod {
+	case trailingPeriodKeep, trailingPeriodStrip, trailingPeriodAdd:
+	default:
+		return fmt.Errorf("invalid schema.trailingPeriod %q (expected %s, %s or %s)", cfg.Schema.TrailingPeriod,
+			trailingPeriodKeep, trailingPeriodStrip, trailingPeriodAdd)
+	}
+	switch cfg.ArrayMerge {
+	case arrayMergeReplace, arrayMergeAppend:
+	default:
+		return fmt.Errorf("invalid arrayMerge %q (expected %s or %s)", cfg.ArrayMerge,
+			arrayMergeReplace, arrayMergeAppend)
+	}
+	switch cfg.BooleanCoercion {
+	case booleanCoercionNone, booleanCoercionCoerce:
+	default:
+		return fmt.Errorf("invalid booleanCoercion %q (expected %s or %s)", cfg.BooleanCoercion,
+			booleanCoercionNone, booleanCoercionCoerce)
+	}
+	switch cfg.Readme.TableStyle {
+	case tableStylePadded, tableStyleCompact:
+	default:
+		return fmt.Errorf("invalid readme.tableStyle %q (expected %s or %s)", cfg.Readme.TableStyle,
+			tableStylePadded, tableStyleCompact)
+	}
+	switch cfg.Readme.ParamSort {
+	case paramSortFile, paramSortAlpha:
+	default:
+		return fmt.Errorf("invalid readme.paramSort %q (expected %s or %s)", cfg.Readme.ParamSort,
+			paramSortFile, paramSortAlpha)
+	}
+	switch cfg.Schema.Dialect {
+	case schemaDialectOpenAPI, schemaDialectDraft07:
+	default:
+		return fmt.Errorf("invalid schema.dialect %q (expected %s or %s)", cfg.Schema.Dialect,
+			schemaDialectOpenAPI, schemaDialectDraft07)
+	}
+	for _, pattern := range cfg.Exclude {
+		if err := checkKeyPattern(pattern); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+	if cfg.Schema.RootType != "" && !schemaTypes[cfg.Schema.RootType] {
+		return fmt.Errorf("invalid schema.rootType %q", cfg.Schema.RootType)
+	}
+	if len(cfg.Readme.Columns) == 0 {
+		return errors.New("readme.columns must list at least one column")
+	}
+	seen := map[string]bool{}
+	for _, c := range cfg.Readme.Columns {
+		switch c {
+		case columnName, columnType, columnRequired, columnDescription, columnValue:
+		default:
+			return fmt.Errorf("invalid readme.columns entry %q (expected %s, %s, %s, %s or %s)", c,
+				columnName, columnType, columnRequired, columnDescription, columnValue)
+		}
+		if seen[c] {
+			return fmt.Errorf("readme.columns lists %q twice", c)
+		}
+		seen[c] = true
+	}
+	return nil
+}
+
+//-------------------------------------------------------------------------
+// YAML utilities – flatten structures into dot notation «key», arrays as key[0]
+//-------------------------------------------------------------------------
+
+// flattenYAML flattens nested YAML to dot-notation keys (a.b[0].c). Keys in
+// whole are kept as one entry holding their entire value.
+func flattenYAML(prefix string, in interface{}, out map[string]interface{}, whole map[string]bool) {
+	if whole[prefix] {
+		out[prefix] = in
+		return
+	}
+	switch v := in.(type) {
+
+	case map[string]interface{}:
+		if len(v) == 0 {
+			if prefix != "" {
+				out[prefix] = v
+			}
+			return
+		}
+		for k, val := range v {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flattenYAML(key, val, out, whole)
+		}
+
+	case []interface{}:
+		if len(v) == 0 {
+			if prefix != "" {
+				out[prefix] = v
+			}
+			return
+		}
+		for i, val := range v {
+			key := fmt.Sprintf("%s[%d]", prefix, i)
+			flattenYAML(key, val, out, whole)
+		}
+
+	default:
+		out[prefix] = v
+	}
+}
+
+//-------------------------------------------------------------------------
+// createValuesObject – converts YAML to []*Parameter with value & type info
+//-------------------------------------------------------------------------
+
+// createValuesObject flattens the merged values files and also returns the
+// merged root value.
+func createValuesObject(files []valuesFile, cfg *Config) ([]*Parameter, interface{}, error) {
+	node, aliases, err := mergedValues(files, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	whole := map[string]bool{}
+	if cfg.Validation.CollapseAliases {
+		for _, path := range slices.Sorted(maps.Keys(aliases)) {
+			whole[path] = true
+			console.Info("%s is an alias of &%s and is documented as a single key", path, aliases[path])
+		}
+	}
+	m := map[string]interface{}{}
+	flattenYAML("", node, m, whole)
+
+	// Build parameters
+	params := []*Parameter{}
+	for path, val := range m {
+		if path == "" {
+			// A scalar root has no key to document.
+			continue
+		}
+		p := NewParameter(path)
+		p.Value = val
+		p.Type = inferType(val)
+		params = append(params, p)
+	}
+	// Sort for deterministic output
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+	// Locate every key in the last file setting it; keys only reached
+	// through an alias or merge key keep no line. Several keys may share a
+	// line, as in a one-line JSON file, so they are collected by name.
+	for _, f := range files {
+		byName := map[string]int{}
+		_ = walkYAMLLeaves(f.data, func(key string, k, _ *yaml.Node) {
+			byName[key] = k.Line
+		})
+		for _, p := range params {
+			if line, ok := byName[p.Name]; ok {
+				p.File, p.Line = f.path, line
+			}
+		}
+		if cfg.Readme.PreserveLiterals {
+			literals, _ := yamlScalarLiterals(f.data)
+			for _, p := range params {
+				if lit, ok := literals[p.Name]; ok {
+					p.Source = lit
+				}
+			}
+		}
+	}
+	for _, p := range params {
+		console.Debug("flattened key %s (%s)", p.Name, p.Type)
+	}
+	return params, node, nil
+}
+
+// rootType is the schema type of a values file root; an empty file is an
+// empty map to Helm.
+func rootType(v interface{}) string {
+	switch t := inferType(v); t {
+	case "array", "string", "boolean", "integer", "number":
+		return t
+	default:
+		return "object"
+	}
+}
+
+// valuesFile is the content of a values file; path locates the files it
+// refers to (@include, fromFile) and names it in errors.
+type valuesFile struct {
+	path string
+	data []byte
+}
+
+// readValuesFiles reads YAML or JSON values files. JSON is valid YAML, so
+// both go through the same decoder; JSON files have no comments, and their
+// metadata comes from --metadata files.
+func readValuesFiles(paths []string) ([]valuesFile, error) {
+	files := make([]valuesFile, 0, len(paths))
+	for _, path := range paths {
+		if strings.EqualFold(filepath.Ext(path), ".toml") {
+			return nil, fmt.Errorf("%s: TOML values files are not supported; use YAML or JSON", path)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, valuesFile{path, data})
+	}
+	return files, nil
+}
+
+// mergedValues decodes the values files and merges them in order. It also
+// returns the aliased maps and lists of every file, as from aliasedCollections.
+func mergedValues(files []valuesFile, cfg *Config) (interface{}, map[string]string, error) {
+	var merged interface{}
+	aliases := map[string]string{}
+	for _, f := range files {
+		values, fileAliases, err := decodeValues(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		// An empty file overrides nothing.
+		if values != nil {
+			merged = mergeValues(merged, values, cfg)
+		}
+		for k, v := range fileAliases {
+			aliases[k] = v
+		}
+	}
+	return merged, aliases, nil
+}
+
+// mergeValues overlays over onto base like Helm merges values files: maps
+// are merged key by key and anything else in over replaces base, except that
+// arrays are concatenated when cfg.ArrayMerge is "append".
+func mergeValues(base, over interface{}, cfg *Config) interface{} {
+	switch o := over.(type) {
+	case map[string]interface{}:
+		b, ok := base.(map[string]interface{})
+		if !ok {
+			return o
+		}
+		out := make(map[string]interface{}, len(b)+len(o))
+		for k, v := range b {
+			out[k] = v
+		}
+		for k, v := range o {
+			if bv, ok := out[k]; ok {
+				v = mergeValues(bv, v, cfg)
+			}
+			out[k] = v
+		}
+		return out
+	case []interface{}:
+		if b, ok := base.([]interface{}); ok && cfg.ArrayMerge == arrayMergeAppend {
+			return append(append([]interface{}{}, b...), o...)
+		}
+	}
+	return over
+}
+
+// decodeValues decodes a values file into plain maps, slices and scalars,
+// rejecting duplicate keys. The aliased maps and lists of the file are
+// returned as well.
+func decodeValues(f valuesFile) (interface{}, map[string]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(f.data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", f.path, err)
+	}
+	if dups := duplicateKeys(&doc); len(dups) > 0 {
+		for _, d := range dups {
+			console.Error("%s:%s", f.path, d)
+		}
+		return nil, nil, errors.New("duplicate keys found")
+	}
+	values, err := nodeToValue(&doc)
+	return values, aliasedCollections(&doc), err
+}
+
+// aliasedCollections maps the flattened path of every alias of a map or list
+// (resources: *limits) to the anchor name. Merge keys are not included: the
+// keys they pull in are keys of the merging map.
+func aliasedCollections(doc *yaml.Node) map[string]string {
+	aliases := map[string]string{}
+	var walk func(prefix string, n *yaml.Node)
+	walk = func(prefix string, n *yaml.Node) {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				walk(prefix, c)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k, v := n.Content[i], n.Content[i+1]
+				if k.ShortTag() == "!!merge" {
+					continue
+				}
+				key := k.Value
+				if prefix != "" {
+					key = prefix + "." + k.Value
+				}
+				walk(key, v)
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				walk(fmt.Sprintf("%s[%d]", prefix, i), c)
+			}
+		case yaml.AliasNode:
+			if n.Alias != nil && (n.Alias.Kind == yaml.MappingNode || n.Alias.Kind == yaml.SequenceNode) {
+				aliases[prefix] = n.Value
+			}
+		}
+	}
+	walk("", doc)
+	return aliases
+}
+
+// nodeToValue converts a YAML node tree into plain Go values. Scalars are
+// decoded according to their YAML tag rather than yaml.v3's implicit
+// resolution, so quoted "3.10" stays a string, an explicit "!!bool yes" is a
+// boolean and timestamps keep their literal text. Aliases and merge keys
+// (<<) are resolved.
+func nodeToValue(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return nodeToValue(n.Content[0])
+	case yaml.AliasNode:
+		return nodeToValue(n.Alias)
+	case yaml.SequenceNode:
+		out := []interface{}{}
+		for _, c := range n.Content {
+			v, err := nodeToValue(c)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case yaml.MappingNode:
+		out := map[string]interface{}{}
+		var merged []map[string]interface{}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			val, err := nodeToValue(v)
+			if err != nil {
+				return nil, err
+			}
+			if k.ShortTag() == "!!merge" {
+				merged = append(merged, mergeSources(val)...)
+				continue
+			}
+			out[k.Value] = val
+		}
+		// Explicit keys win over merged ones; earlier merge sources win
+		// over later ones.
+		for _, src := range merged {
+			for k, v := range src {
+				if _, ok := out[k]; !ok {
+					out[k] = v
+				}
+			}
+		}
+		return out, nil
+	default:
+		return scalarValue(n)
+	}
+}
+
+// duplicateKeys lists every mapping key defined more than once at the same
+// level, as "<line>: ..." messages. Aliases are not followed, so a reused
+// anchor is only inspected where it is defined.
+func duplicateKeys(n *yaml.Node) []string {
+	var out []string
+	if n.Kind == yaml.MappingNode {
+		seen := map[string]int{}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			if k.ShortTag() == "!!merge" {
+				continue
+			}
+			if first, ok := seen[k.Value]; ok {
+				out = append(out, fmt.Sprintf("%d: duplicate key %q (first defined at line %d)", k.Line, k.Value, first))
+				continue
+			}
+			seen[k.Value] = k.Line
+		}
+	}
+	for _, c := range n.Content {
+		out = append(out, duplicateKeys(c)...)
+	}
+	return out
+}
+
+// mergeSources returns the mappings referenced by a merge key, which may be
+// a single mapping or a sequence of them.
+func mergeSources(v interface{}) []map[string]interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{vv}
+	case []interface{}:
+		var out []map[string]interface{}
+		for _, e := range vv {
+			out = append(out, mergeSources(e)...)
+		}
+		return out
+	}
+	return nil
+}
+
+func scalarValue(n *yaml.Node) (interface{}, error) {
+	switch n.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		// Accept the YAML 1.1 spellings when the author tagged them.
+		switch strings.ToLower(n.Value) {
+		case "true", "yes", "y", "on":
+			return true, nil
+		case "false", "no", "n", "off":
+			return false, nil
+		}
+		return nil, fmt.Errorf("line %d: cannot decode %q as a boolean", n.Line, n.Value)
+	case "!!int", "!!float":
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	default:
+		// !!str, !!timestamp, !!binary and custom tags keep their text.
+		return n.Value, nil
+	}
+}
+
+func inferType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64:
+		return "integer"
+	case float64:
+		// Floats stay numbers even when integral (1.0, 1e3), as written.
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "unknown"
+	}
+}
+
+//-------------------------------------------------------------------------
+// utils helpers similar to lib/utils.js
+//-------------------------------------------------------------------------
+
+func getArrayPrefix(path string) string {
+	idx := strings.Index(path, "[")
+	if idx == -1 {
+		return path
+	}
+	return path[:idx]
+}
+
+func sanitizeProperty(path string) string {
+	if strings.Contains(path, "[") {
+		return getArrayPrefix(path)
+	}
+	return path
+}
+
+//-------------------------------------------------------------------------
+// parseMetadataComments – reads YAML file line by line and extracts @param, @section etc.
+//-------------------------------------------------------------------------
+
+// parseMetadataComments reads the comments of every values file in order, as
+// if the files were concatenated: a section started in one file goes on in
+// the next until another @section.
+func parseMetadataComments(files []valuesFile, cfg *Config) (*Metadata, error) {
+	m := &Metadata{}
+	var current *Section
+
+	// Pre‑build regexps
+	regParam := regexp.MustCompile(fmt.Sprintf(`^\s*%s\s*%s\s*([^\s]+)\s*(.*)$`,
+		regexp.QuoteMeta(cfg.Comments.Format), regexp.QuoteMeta(cfg.Tags.Param)))
+	regSection := regexp.MustCompile(fmt.Sprintf(`^\s*%s\s*%s\s*(.*)$`,
+		regexp.QuoteMeta(cfg.Comments.Format), regexp.QuoteMeta(cfg.Tags.Section)))
+	regDescStart := regexp.MustCompile(fmt.Sprintf(`^\s*%s\s*%s\s*(.*)$`,
+		regexp.QuoteMeta(cfg.Comments.Format), regexp.QuoteMeta(cfg.Tags.DescriptionStart)))
+	regDescEnd := regexp.MustCompile(fmt.Sprintf(`^\s*%s\s*%s`,
+		regexp.QuoteMeta(cfg.Comments.Format), regexp.QuoteMeta(cfg.Tags.DescriptionEnd)))
+	regDescContent := regexp.MustCompile(fmt.Sprintf(`^\s*%s\s?(.*)`, regexp.QuoteMeta(cfg.Comments.Format)))
+	regSkip := regexp.MustCompile(fmt.Sprintf(`^\s*%s\s*%s\s*([^\s]+)\s*(.*?)\s*$`,
+		regexp.QuoteMeta(cfg.Comments.Format), regexp.QuoteMeta(cfg.Tags.Skip)))
+	regExtra := regexp.MustCompile(fmt.Sprintf(`^\s*%s\s*%s\s*([^\s]+)\s*(\[.*?\])?\s*(.*)$`,
+		regexp.QuoteMeta(cfg.Comments.Format), regexp.QuoteMeta(cfg.Tags.Extra)))
+	regDefault := regexp.MustCompile(fmt.Sprintf(`^\s*%s\s*%s\s+(.*?)\s*$`,
+		regexp.QuoteMeta(cfg.Comments.Format), regexp.QuoteMeta(cfg.Tags.Default)))
+	// The start tag must end there, as it is a prefix of the default end tag.
+	regExampleStart := regexp.MustCompile(fmt.Sprintf(`^\s*%s\s*%s(?:\s+(.*))?$`,
+		regexp.QuoteMeta(cfg.Comments.Format), regexp.QuoteMeta(cfg.Tags.ExampleStart)))
+	regExampleEnd := regexp.MustCompile(fmt.Sprintf(`^\s*%s\s*%s\s*$`,
+		regexp.QuoteMeta(cfg.Comments.Format), regexp.QuoteMeta(cfg.Tags.ExampleEnd)))
+	regAlias := regexp.MustCompile(fmt.Sprintf(`^%s\s+([^\s]+)\s*(.*)$`, regexp.QuoteMeta(cfg.Tags.Alias)))
+	regNested := regexp.MustCompile(`^(#+)\s+(.*)$`)
+
+	for _, f := range files {
+		reader := bufio.NewReader(bytes.NewReader(f.data))
+		console.Debug("reading metadata from %s", f.path)
+
+		// Lines of the leaf keys a plain comment may document.
+		var keyLines map[int]string
+		if cfg.Comments.PlainAsDescription {
+			var err error
+			if keyLines, err = yamlLeafKeyLines(f.data); err != nil {
+				return nil, err
+			}
+		}
+		var descriptionMode bool
+		// The @param whose @example block is being read.
+		var example *Parameter
+		var plain []string
+		// The @param that a following @default applies to.
+		var lastParam *Parameter
+		// The @param whose description the next plain comment line
+		// continues.
+		var continued *Parameter
+
+		lineNo := 0
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			lineNo++
+			trimmed := strings.TrimRight(line, "\r\n")
+			prevParam, continuation := continued, false
+			continued = nil
+
+			switch {
+			case example != nil && regExampleEnd.MatchString(trimmed):
+				console.Debug("line %d: example end", lineNo)
+				example = nil
+
+			case example != nil && regDescContent.MatchString(trimmed):
+				console.Debug("line %d: example content", lineNo)
+				example.ExampleLines = append(example.ExampleLines, regDescContent.FindStringSubmatch(trimmed)[1])
+
+			case regExampleStart.MatchString(trimmed):
+				if lastParam == nil {
+					return nil, fmt.Errorf("%s:%d: %s without a preceding %s", f.path, lineNo, cfg.Tags.ExampleStart, cfg.Tags.Param)
+				}
+				console.Debug("line %d: example of %s", lineNo, lastParam.Name)
+				example = lastParam
+				// Further examples of the same parameter share its code
+				// block, one blank line apart.
+				if len(example.ExampleLines) > 0 {
+					example.ExampleLines = append(example.ExampleLines, "")
+				}
+				if first := regExampleStart.FindStringSubmatch(trimmed)[1]; first != "" {
+					example.ExampleLines = append(example.ExampleLines, first)
+				}
+
+			case regSection.MatchString(trimmed):
+				name := strings.TrimSpace(regSection.FindStringSubmatch(trimmed)[1])
+				level := 0
+				if nm := regNested.FindStringSubmatch(name); nm != nil {
+					level, name = len(nm[1]), nm[2]
+				}
+				current = &Section{Name: name, Level: level}
+				m.AddSection(current)
+				descriptionMode = false
+				console.Debug("line %d: section %q (level %d)", lineNo, name, level)
+
+			case regDescStart.MatchString(trimmed):
+				console.Debug("line %d: description start", lineNo)
+				descriptionMode = true
+				if current != nil {
+					first := regDescStart.FindStringSubmatch(trimmed)[1]
+					if first != "" {
+						current.DescriptionLines = append(current.DescriptionLines, first)
+					}
+				}
+
+			case descriptionMode && regDescEnd.MatchString(trimmed):
+				console.Debug("line %d: description end", lineNo)
+				descriptionMode = false
+
+			case descriptionMode && regDescContent.MatchString(trimmed):
+				console.Debug("line %d: description content", lineNo)
+				if current != nil {
+					txt := regDescContent.FindStringSubmatch(trimmed)[1]
+					if path, ok := includeDirective(txt, cfg); ok {
+						lines, err := includeFile(path, filepath.Dir(f.path), cfg, nil)
+						if err != nil {
+							return nil, fmt.Errorf("line %d: %w", lineNo, err)
+						}
+						console.Debug("line %d: included %s (%d lines)", lineNo, path, len(lines))
+						current.DescriptionLines = append(current.DescriptionLines, lines...)
+						continue
+					}
+					current.DescriptionLines = append(current.DescriptionLines, txt)
+				}
+
+			case regParam.MatchString(trimmed):
+				sm := regParam.FindStringSubmatch(trimmed)
+				p := NewParameter(sm[1])
+				p.File, p.Line = f.path, lineNo
+				rest := sm[2]
+				if am := regAlias.FindStringSubmatch(rest); am != nil {
+					p.DisplayName, rest = am[1], am[2]
+				}
+				p.Modifiers, p.Description = splitModifiers(rest)
+				p.Modifiers = joinExampleCode(p.Modifiers, cfg)
+				p.Modifiers = joinEnum(p.Modifiers, cfg)
+				if list, ok := p.ModifierValue(cfg.Modifiers.Enum); ok {
+					for _, v := range strings.Split(list, ",") {
+						p.Enum = append(p.Enum, strings.TrimSpace(v))
+					}
+				}
+				if current != nil {
+					p.Section = current.Name
+					current.Parameters = append(current.Parameters, p)
+				}
+				m.AddParameter(p)
+				lastParam = p
+				if cfg.Comments.MultilineDescriptions {
+					continued = p
+				}
+				console.Debug("line %d: param %s modifiers=%v section=%q", lineNo, p.Name, p.Modifiers, p.Section)
+
+			case regDefault.MatchString(trimmed):
+				if lastParam == nil {
+					return nil, fmt.Errorf("%s:%d: %s without a preceding %s", f.path, lineNo, cfg.Tags.Default, cfg.Tags.Param)
+				}
+				lastParam.DisplayValue = regDefault.FindStringSubmatch(trimmed)[1]
+				console.Debug("line %d: default of %s is %q", lineNo, lastParam.Name, lastParam.DisplayValue)
+
+			case regSkip.MatchString(trimmed):
+				sm := regSkip.FindStringSubmatch(trimmed)
+				console.Debug("line %d: skip %s", lineNo, sm[1])
+				p := NewParameter(sm[1])
+				p.File, p.Line = f.path, lineNo
+				p.SkipReason = sm[2]
+				p.SetSkip(true)
+				if current != nil {
+					p.Section = current.Name
+					current.Parameters = append(current.Parameters, p)
+					if p.SkipReason != "" {
+						current.Skipped = append(current.Skipped, p)
+					}
+				}
+				m.AddParameter(p)
+
+			case regExtra.MatchString(trimmed):
+				sm := regExtra.FindStringSubmatch(trimmed)
+				p := NewParameter(sm[1])
+				p.File, p.Line = f.path, lineNo
+				p.Description = sm[3]
+				p.Value = "" // empty string
+				p.SetExtra(true)
+				if current != nil {
+					p.Section = current.Name
+					current.Parameters = append(current.Parameters, p)
+				}
+				m.AddParameter(p)
+				console.Debug("line %d: extra %s section=%q", lineNo, p.Name, p.Section)
+
+			case prevParam != nil && isPlainComment(trimmed, regDescContent, cfg):
+				// Plain comment lines right below a @param continue its
+				// description, up to an empty comment line.
+				if txt := strings.TrimSpace(regDescContent.FindStringSubmatch(trimmed)[1]); txt != "" {
+					prevParam.Description = strings.TrimSpace(prevParam.Description + " " + txt)
+					continued, continuation = prevParam, true
+					console.Debug("line %d: description of %s continued", lineNo, prevParam.Name)
+				}
+
+			default:
+				if key, ok := keyLines[lineNo]; ok && len(plain) > 0 {
+					p := NewParameter(key)
+					p.File, p.Line = f.path, lineNo
+					p.Description = strings.Join(plain, " ")
+					p.Implicit = true
+					if current != nil {
+						p.Section = current.Name
+						current.Parameters = append(current.Parameters, p)
+					}
+					m.AddParameter(p)
+					console.Debug("line %d: implicit param %s section=%q", lineNo, p.Name, p.Section)
+				} else if trimmed != "" {
+					console.Debug("line %d: no metadata", lineNo)
+				}
+			}
+
+			if keyLines != nil && !descriptionMode && example == nil && !continuation && isPlainComment(trimmed, regDescContent, cfg) {
+				plain = append(plain, strings.TrimSpace(regDescContent.FindStringSubmatch(trimmed)[1]))
+			} else {
+				plain = nil
+			}
+
+			if err == io.EOF {
+				break
+			}
+		}
+	}
+	if cfg.Comments.PlainAsDescription {
+		m.dropShadowedImplicit()
+	}
+	return m, nil
+}
+
+// isPlainComment reports whether line is a metadata-format comment that
+// carries none of the configured tags.
+func isPlainComment(line string, regComment *regexp.Regexp, cfg *Config) bool {
+	if !regComment.MatchString(line) {
+		return false
+	}
+	for _, tag := range []string{cfg.Tags.Param, cfg.Tags.Section, cfg.Tags.DescriptionStart,
+		cfg.Tags.DescriptionEnd, cfg.Tags.Skip, cfg.Tags.Extra, cfg.Tags.Default,
+		cfg.Tags.ExampleStart, cfg.Tags.ExampleEnd} {
+		if strings.Contains(line, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// dropShadowedImplicit removes plain-comment parameters for keys that also
+// have explicit metadata anywhere in the file; the tag always wins.
+func (m *Metadata) dropShadowedImplicit() {
+	explicit := map[string]bool{}
+	for _, p := range m.Parameters {
+		if !p.Implicit {
+			explicit[p.Name] = true
+		}
+	}
+	keep := func(list []*Parameter) []*Parameter {
+		out := list[:0]
+		for _, p := range list {
+			if !p.Implicit || !explicit[p.Name] {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+	m.Parameters = keep(m.Parameters)
+	for _, sec := range m.Sections {
+		sec.Parameters = keep(sec.Parameters)
+	}
+}
+
+// dropParameters removes the given parameters from the metadata and its
+// sections.
+func (m *Metadata) dropParameters(drop []*Parameter) {
+	if len(drop) == 0 {
+		return
+	}
+	dropped := map[*Parameter]bool{}
+	for _, p := range drop {
+		dropped[p] = true
+	}
+	keep := func(list []*Parameter) []*Parameter {
+		out := list[:0]
+		for _, p := range list {
+			if !dropped[p] {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+	m.Parameters = keep(m.Parameters)
+	for _, sec := range m.Sections {
+		sec.Parameters = keep(sec.Parameters)
+	}
+}
+
+// yamlLeafKeyLines maps the line of every mapping key holding a scalar or an
+// empty collection to its flattened path, matching the keys of flattenYAML.
+func yamlLeafKeyLines(raw []byte) (map[int]string, error) {
+	lines := map[int]string{}
+	err := walkYAMLLeaves(raw, func(key string, k, _ *yaml.Node) {
+		lines[k.Line] = key
+	})
+	return lines, err
+}
+
+// yamlScalarLiterals maps the flattened path of every mapping key holding a
+// scalar to scalarLiteral of that scalar; "" when it is shown as decoded.
+func yamlScalarLiterals(raw []byte) (map[string]string, error) {
+	literals := map[string]string{}
+	err := walkYAMLLeaves(raw, func(key string, _, v *yaml.Node) {
+		if v.Kind == yaml.AliasNode {
+			v = v.Alias
+		}
+		if v.Kind == yaml.ScalarNode {
+			literals[key] = scalarLiteral(v)
+		}
+	})
+	return literals, err
+}
+
+// scalarLiteral returns the text of a scalar as written when the README
+// would otherwise show it differently: numbers in another notation (0x1F,
+// 1_000, 1.0) and quoted strings that would read as another type unquoted
+// ("3.10", 'true').
+func scalarLiteral(n *yaml.Node) string {
+	switch n.ShortTag() {
+	case "!!int", "!!float":
+		var v interface{}
+		if n.Decode(&v) == nil && fmt.Sprint(v) != n.Value {
+			return n.Value
+		}
+	case "!!str":
+		if n.Value == "" || n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) == 0 {
+			break
+		}
+		if plain := (&yaml.Node{Kind: yaml.ScalarNode, Value: n.Value}); plain.ShortTag() != "!!str" {
+			if n.Style&yaml.SingleQuotedStyle != 0 {
+				return "'" + n.Value + "'"
+			}
+			return `"` + n.Value + `"`
+		}
+	}
+	return ""
+}
+
+// walkYAMLLeaves calls fn with the flattened path, key node and value node
+// of every mapping key holding a scalar or an empty collection.
+func walkYAMLLeaves(raw []byte, fn func(key string, k, v *yaml.Node)) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	var walk func(prefix string, n *yaml.Node)
+	walk = func(prefix string, n *yaml.Node) {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				walk(prefix, c)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k, v := n.Content[i], n.Content[i+1]
+				// Merged keys are documented where they are defined.
+				if k.ShortTag() == "!!merge" {
+					continue
+				}
+				key := k.Value
+				if prefix != "" {
+					key = prefix + "." + k.Value
+				}
+				// An alias is a leaf only when it points at a scalar; its
+				// keys belong to the anchor's lines.
+				if v.Kind == yaml.AliasNode {
+					if v.Alias != nil && v.Alias.Kind == yaml.ScalarNode {
+						fn(key, k, v)
+					}
+					continue
+				}
+				if v.Kind == yaml.ScalarNode || len(v.Content) == 0 {
+					fn(key, k, v)
+					continue
+				}
+				walk(key, v)
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				walk(fmt.Sprintf("%s[%d]", prefix, i), c)
+			}
+		}
+	}
+	walk("", &doc)
+	return nil
+}
+
+// includeDirective reports whether a description line is "@include <path>".
+func includeDirective(line string, cfg *Config) (string, bool) {
+	f := strings.Fields(line)
+	if len(f) != 2 || f[0] != cfg.Tags.Include {
+		return "", false
+	}
+	return f[1], true
+}
+
+// includeFile returns the lines of path, resolved relative to dir, with
+// nested @include lines expanded relative to the including file. stack holds
+// the files being included and is used to reject cycles.
+func includeFile(path, dir string, cfg *Config, stack []string) ([]string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+	for i, p := range stack {
+		if p == path {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack[i:], path), " -> "))
+		}
+	}
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+	stack = append(stack, path)
+	var out []string
+	for _, l := range strings.Split(strings.TrimRight(string(raw), "\r\n"), "\n") {
+		l = strings.TrimRight(l, "\r")
+		if nested, ok := includeDirective(l, cfg); ok {
+			lines, err := includeFile(nested, filepath.Dir(path), cfg, stack)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, lines...)
+			continue
+		}
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+// joinExampleCode glues the modifiers following an "example-code" one back
+// onto it, so commas in the snippet do not split it, and trims them all.
+func joinExampleCode(mods []string, cfg *Config) []string {
+	for i, m := range mods {
+		if strings.HasPrefix(strings.TrimSpace(m), cfg.Modifiers.ExampleCode+":") {
+			mods = append(mods[:i], strings.Join(mods[i:], ","))
+			break
+		}
+	}
+	for i, m := range mods {
+		mods[i] = strings.TrimSpace(m)
+	}
+	return mods
+}
+
+// joinEnum glues the modifiers following an "enum" one back onto it up to
+// the next configured modifier, so "[enum:a,b,nullable]" lists a and b.
+func joinEnum(mods []string, cfg *Config) []string {
+	known := configuredModifiers(cfg)
+	for i, m := range mods {
+		if !strings.HasPrefix(m, cfg.Modifiers.Enum+":") {
+			continue
+		}
+		j := i + 1
+		for ; j < len(mods); j++ {
+			if name, _, _ := strings.Cut(mods[j], ":"); known[name] {
+				break
+			}
+		}
+		out := append(mods[:i:i], strings.Join(mods[i:j], ","))
+		return append(out, mods[j:]...)
+	}
+	return mods
+}
+
+// configuredModifiers returns the set of modifier names in cfg.Modifiers.
+func configuredModifiers(cfg *Config) map[string]bool {
+	known := map[string]bool{}
+	mods := reflect.ValueOf(cfg.Modifiers)
+	for i := 0; i < mods.NumField(); i++ {
+		if name := mods.Field(i).String(); name != "" {
+			known[name] = true
+		}
+	}
+	return known
+}
+
+// splitModifiers separates an optional leading "[mod1,mod2]" block from the
+// description. Brackets are matched by depth and modifiers are only split on
+// top-level commas, so values such as "pattern:^[a-z]{1,3}$" survive intact.
+// The modifiers keep their surrounding spaces for joinExampleCode.
+func splitModifiers(rest string) ([]string, string) {
+	if !strings.HasPrefix(rest, "[") {
+		return nil, rest
+	}
+	var mods []string
+	depth, start := 0, 1
+	for i, r := range rest {
+		switch r {
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+		case ',':
+			if depth == 1 {
+				mods = append(mods, rest[start:i])
+				start = i + 1
+			}
+		}
+		if depth == 0 {
+			if last := rest[start:i]; strings.TrimSpace(last) != "" || len(mods) > 0 {
+				mods = append(mods, last)
+			}
+			return mods, strings.TrimSpace(rest[i+1:])
+		}
+	}
+	// Unbalanced brackets: treat the whole text as description.
+	return nil, rest
+}
+
+//-------------------------------------------------------------------------
+// checker – verifies that metadata ↔ actual keys match
+//-------------------------------------------------------------------------
+
+// keyCheck is the outcome of comparing the keys of values.yaml with the
+// documented ones.
+type keyCheck struct {
+	missing []*Parameter // present in YAML, absent in metadata
+	orphan  []*Parameter // present in metadata, absent in YAML
+}
+
+// KeyReport lists the keys whose metadata does not line up with values.yaml,
+// so that callers can choose how to treat each kind.
+type KeyReport struct {
+	// Missing are keys of values.yaml without metadata, an error unless
+	// validation.noFail is set.
+	Missing []string
+	// Orphan are documented keys absent from values.yaml, a warning; they
+	// are left out of the README and schema.
+	Orphan []string
+}
+
+// KeyError is returned, possibly joined with other errors, when keys lack
+// metadata. Use errors.As to tell it from other failures.
+type KeyError struct {
+	KeyReport
+}
+
+func (e *KeyError) Error() string { return "metadata errors found" }
+
+// keyReport returns the names of the mismatched keys.
+func (k keyCheck) keyReport() KeyReport {
+	var r KeyReport
+	for _, p := range k.missing {
+		r.Missing = append(r.Missing, p.Name)
+	}
+	for _, p := range k.orphan {
+		r.Orphan = append(r.Orphan, p.Name)
+	}
+	return r
+}
+
+// checkKeys verifies that each actual YAML key has matching metadata and vice-versa,
+// but skips entire sub-trees for parameters marked with @skip or any modifier.
+func checkKeys(real []*Parameter, meta []*Parameter) keyCheck {
+	// names that cancel validation for themselves and their children
+	skipNames := map[string]struct{}{}
+	for _, p := range meta {
+		if p.Skip() || len(p.Modifiers) > 0 { // modifier implies object/array parent
+			skipNames[sanitizeProperty(p.Name)] = struct{}{}
+			console.Debug("check: not validating subtree %s", sanitizeProperty(p.Name))
+		}
+	}
+
+	// helper: does name fall under a skipped prefix?
+	isSkipped := func(name string) bool {
+		for sk := range skipNames {
+			if name == sk ||
+				strings.HasPrefix(name, sk+".") ||
+				strings.HasPrefix(name, sk+"[") {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Keys with a <placeholder> segment document every entry of a map with
+	// user-chosen names; they match keys instead of naming one. They also
+	// document the map itself when it is empty, as in "extraVolumes: {}".
+	var patterns []*regexp.Regexp
+	placeholderMaps := map[string]bool{}
+	realKeys, metaKeys := []string{}, []string{}
+	for _, p := range meta {
+		if p.Extra() {
+			continue
+		}
+		// Checked before isSkipped: a modifier on a placeholder key, as in
+		// "labels.<name> [string]", skips its own subtree, not the map.
+		if re := placeholderPattern(p.Name); re != nil {
+			patterns = append(patterns, re)
+			if i := strings.Index(p.Name, ".<"); i > 0 {
+				placeholderMaps[p.Name[:i]] = true
+			}
+			continue
+		}
+		if isSkipped(p.Name) {
+			continue
+		}
+		metaKeys = append(metaKeys, p.Name)
+	}
+	matchesPattern := func(name string) bool {
+		for _, re := range patterns {
+			if re.MatchString(name) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range real {
+		if m, ok := p.Value.(map[string]interface{}); ok && len(m) == 0 && placeholderMaps[p.Name] {
+			continue
+		}
+		if !isSkipped(p.Name) && !p.Extra() && !matchesPattern(p.Name) {
+			realKeys = append(realKeys, p.Name)
+		}
+	}
+
+	at := func(list []*Parameter, names []string) []*Parameter {
+		var out []*Parameter
+		for _, name := range names {
+			for _, p := range list {
+				if p.Name == name {
+					out = append(out, p)
+					break
+				}
+			}
+		}
+		return out
+	}
+	return keyCheck{
+		missing: at(real, difference(realKeys, metaKeys)),
+		orphan:  at(meta, difference(metaKeys, realKeys)),
+	}
+}
+
+// report logs the result. Metadata for keys that do not exist is only a
+// warning, since it is harmless while a key is temporarily removed; missing
+// metadata is an error unless validation.noFail is set. --strict turns the
+// warnings into errors again.
+func (k keyCheck) report(cfg *Config) error {
+	if len(k.missing) == 0 && len(k.orphan) == 0 {
+		console.Info("Metadata is correct!")
+		return nil
+	}
+	for _, p := range k.missing {
+		if cfg.Validation.NoFail {
+			console.WarnAt(p.File, p.Line, "Missing metadata for key: %s", p.Name)
+		} else {
+			console.ErrorAt(p.File, p.Line, "Missing metadata for key: %s", p.Name)
+		}
+	}
+	for _, p := range k.orphan {
+		console.WarnAt(p.File, p.Line, "Metadata provided for non existing key: %s (left out of the README and schema)", p.Name)
+	}
+	if len(k.missing) > 0 && !cfg.Validation.NoFail {
+		return &KeyError{k.keyReport()}
+	}
+	return nil
+}
+
+// readExcludeFile reads the key patterns of an exclude file, one per line.
+// Blank lines and lines starting with '#' are ignored, as in .helmignore.
+func readExcludeFile(file string) ([]string, error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var patterns []string
+	for i, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := checkKeyPattern(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid pattern %q: %w", file, i+1, line, err)
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, nil
+}
+
+// keySegment is one step of a dot-notation key: a key, or the index of an
+// array entry, written "[0]" after its key.
+type keySegment struct {
+	name  string
+	index bool
+}
+
+// keySegments splits a key, or a key pattern, into its segments: at the dots,
+// and before each "[...]" that ends a segment, so "hosts[0].name" is hosts,
+// [0] and name. A bracket that starts a segment, as in "[ab]c", remains a
+// path.Match character class.
+func keySegments(key string) []keySegment {
+	var out []keySegment
+	for _, part := range strings.Split(key, ".") {
+		var indexes []keySegment
+		for strings.HasSuffix(part, "]") {
+			i := strings.LastIndex(part, "[")
+			if i <= 0 {
+				break
+			}
+			indexes = append(indexes, keySegment{name: part[i+1 : len(part)-1], index: true})
+			part = part[:i]
+		}
+		out = append(out, keySegment{name: part})
+		for i := len(indexes) - 1; i >= 0; i-- {
+			out = append(out, indexes[i])
+		}
+	}
+	return out
+}
+
+// checkKeyPattern reports a malformed segment of a key pattern.
+func checkKeyPattern(pattern string) error {
+	for _, seg := range keySegments(pattern) {
+		if _, err := path.Match(seg.name, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchKeyPattern reports whether a dot-notation key matches pattern. Each
+// segment is matched with path.Match, so "*" stands for one key and "[*]"
+// for any entry of an array; a "**" segment stands for any number of keys
+// and indexes, none included, so "global.**" matches global and everything
+// below it.
+func matchKeyPattern(pattern, key string) bool {
+	var match func(pat, segs []keySegment) bool
+	match = func(pat, segs []keySegment) bool {
+		if len(pat) == 0 {
+			return len(segs) == 0
+		}
+		if pat[0] == (keySegment{name: "**"}) {
+			for i := 0; i <= len(segs); i++ {
+				if match(pat[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 || pat[0].index != segs[0].index {
+			return false
+		}
+		ok, _ := path.Match(pat[0].name, segs[0].name)
+		return ok && match(pat[1:], segs[1:])
+	}
+	return match(keySegments(pattern), keySegments(key))
+}
+
+// excludedParameters returns the parameters matching one of the exclude
+// patterns. Exclusion wins over metadata: a documented key that matches is
+// dropped as well.
+func excludedParameters(list []*Parameter, cfg *Config) []*Parameter {
+	var out []*Parameter
+	for _, p := range list {
+		for _, pattern := range cfg.Exclude {
+			if matchKeyPattern(pattern, p.Name) {
+				console.Debug("exclude: %s matches %s", p.Name, pattern)
+				out = append(out, p)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// excludeParameters returns list without the excluded parameters.
+func excludeParameters(list []*Parameter, cfg *Config) []*Parameter {
+	drop := excludedParameters(list, cfg)
+	if len(drop) == 0 {
+		return list
+	}
+	return slices.DeleteFunc(list, func(p *Parameter) bool { return slices.Contains(drop, p) })
+}
+
+func difference(a, b []string) []string {
+	m := map[string]struct{}{}
+	for _, x := range b {
+		m[x] = struct{}{}
+	}
+	var diff []string
+	for _, x := range a {
+		if _, ok := m[x]; !ok {
+			diff = append(diff, x)
+		}
+	}
+	return diff
+}
+
+//-------------------------------------------------------------------------
+// builder – combine values & metadata, apply modifiers, etc.
+//-------------------------------------------------------------------------
+
+func combineMetadataAndValues(values []*Parameter, meta []*Parameter) {
+	for _, p := range meta {
+		if p.Extra() { // skip
+			continue
+		}
+		for _, src := range values {
+			if src.Name == p.Name {
+				if p.Value == nil {
+					p.Value, p.Source = src.Value, src.Source
+				}
+				p.Type = src.Type
+				p.Schema = src.Schema
+				break
+			}
+		}
+		// A placeholder key has no value of its own; the first entry it
+		// matches tells its type.
+		if re := placeholderPattern(p.Name); re != nil && p.Type == "" {
+			for _, src := range values {
+				if re.MatchString(src.Name) {
+					p.Type = src.Type
+					break
+				}
+			}
+		}
+	}
+
+	// Add skip parameters that are only in values (objects without @param)
+	for _, src := range values {
+		found := false
+		for _, p := range meta {
+			if p.Name == src.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			// Insert after the closest parent
+			np := *src
+			np.SetSkip(true)
+			meta = append(meta, &np)
+		}
+	}
+}
+
+// applyModifiers only needs array/object/string/nullable/default for README/schema rendering.
+func applyModifiers(p *Parameter, cfg *Config) error {
+	if len(p.Modifiers) == 0 {
+		return nil
+	}
+	if err := checkModifierValues(p, cfg); err != nil {
+		return err
+	}
+	nullableLast := false
+	if p.HasModifier(cfg.Modifiers.Nullable) && p.Modifiers[len(p.Modifiers)-1] == cfg.Modifiers.Nullable {
+		nullableLast = true
+	}
+	// A required value keeps what is written: when it is unset it stays
+	// null, so that it is shown as such rather than as the empty default of
+	// its type.
+	keepValue := nullableLast || p.HasModifier(cfg.Modifiers.Required)
+	// The literal only describes the value as written.
+	written := p.Value
+	defer func() {
+		if !reflect.DeepEqual(p.Value, written) {
+			p.Source = ""
+		}
+	}()
+	for _, m := range p.Modifiers {
+		switch m {
+		case cfg.Modifiers.Array:
+			apply, err := resolveTypeConflict(p, m, "array", keepValue, cfg)
+			if err != nil {
+				return err
+			}
+			if apply {
+				p.Type = "array"
+				if !keepValue {
+					p.Value = []interface{}{}
+				}
+			}
+		case cfg.Modifiers.Object:
+			apply, err := resolveTypeConflict(p, m, "object", keepValue, cfg)
+			if err != nil {
+				return err
+			}
+			if apply {
+				p.Type = "object"
+				if !keepValue {
+					p.Value = map[string]interface{}{}
+				}
+			}
+		case cfg.Modifiers.String:
+			apply, err := resolveTypeConflict(p, m, "string", keepValue, cfg)
+			if err != nil {
+				return err
+			}
+			if apply {
+				p.Type = "string"
+				if !keepValue {
+					p.Value = ""
+				}
+			}
+		case cfg.Modifiers.Boolean:
+			if b, ok := booleanLike(p.Value); ok && cfg.BooleanCoercion == booleanCoercionCoerce && p.Type != "boolean" {
+				raw, _ := json.Marshal(p.Value)
+				p.Literal = string(raw)
+				p.Value, p.Type = b, "boolean"
+				break
+			}
+			apply, err := resolveTypeConflict(p, m, "boolean", true, cfg)
+			if err != nil {
+				return err
+			}
+			if apply {
+				p.Type = "boolean"
+			}
+		case cfg.Modifiers.Password:
+			apply, err := resolveTypeConflict(p, m, "string", true, cfg)
+			if err != nil {
+				return err
+			}
+			if apply {
+				p.Type = "string"
+			}
+		case cfg.Modifiers.Percentage:
+			// Whole floats (80.0, 1e2) are refined to integers rather
+			// than conflicting; 80.5 is no integer and does conflict.
+			if f, ok := p.Value.(float64); ok && f == math.Trunc(f) {
+				p.Type = "integer"
+				break
+			}
+			apply, err := resolveTypeConflict(p, m, "integer", true, cfg)
+			if err != nil {
+				return err
+			}
+			if apply {
+				p.Type = "integer"
+			}
+		case cfg.Modifiers.Nullable:
+			if p.Value == nil {
+				p.Value = "nil"
+			}
+		default:
+			// default:<val>
+			if strings.HasPrefix(m, cfg.Modifiers.Default+":") {
+				p.Value = strings.TrimSpace(strings.TrimPrefix(m, cfg.Modifiers.Default+":"))
+			}
+			// type:<t1>|<t2>…
+			if strings.HasPrefix(m, cfg.Modifiers.Type+":") {
+				types := strings.Split(strings.TrimPrefix(m, cfg.Modifiers.Type+":"), "|")
+				for i, t := range types {
+					types[i] = strings.TrimSpace(t)
+					if !schemaTypes[types[i]] {
+						return fmt.Errorf("%s: unknown type %q in modifier %q", p.Name, types[i], m)
+					}
+				}
+				p.Type = strings.Join(types, "|")
+			}
+		}
+	}
+	return nil
+}
+
+// checkModifierValues rejects "name:value" modifiers whose value is empty,
+// such as a mistyped "[default:]", and patterns that do not compile.
+func checkModifierValues(p *Parameter, cfg *Config) error {
+	for _, name := range []string{
+		cfg.Modifiers.Default, cfg.Modifiers.PropertyNames, cfg.Modifiers.OneOfGroup,
+		cfg.Modifiers.DefaultRef, cfg.Modifiers.FromFile, cfg.Modifiers.IfRequired,
+		cfg.Modifiers.Type, cfg.Modifiers.ExampleCode, cfg.Modifiers.Deprecated,
+		cfg.Modifiers.Stability, cfg.Modifiers.K8sType, cfg.Modifiers.AllowedVersions,
+		cfg.Modifiers.Pattern, cfg.Modifiers.ErrorMessage, cfg.Modifiers.Enum,
+	} {
+		v, ok := p.ModifierValue(name)
+		if !ok {
+			continue
+		}
+		if v == "" {
+			return fmt.Errorf("%s: modifier %q needs a value (%s:<value>)", p.Name, name, name)
+		}
+		if name == cfg.Modifiers.PropertyNames || name == cfg.Modifiers.Pattern {
+			if _, err := regexp.Compile(v); err != nil {
+				return fmt.Errorf("%s: invalid %s pattern: %w", p.Name, name, err)
+			}
+		}
+		if name == cfg.Modifiers.Deprecated {
+			if _, _, err := deprecation(p, cfg); err != nil {
+				return err
+			}
+		}
+		if name == cfg.Modifiers.Enum && slices.Contains(p.Enum, "") {
+			return fmt.Errorf("%s: enum %q has an empty entry", p.Name, v)
+		}
+		if name == cfg.Modifiers.Stability && !slices.Contains(cfg.StabilityLevels, v) {
+			return fmt.Errorf("%s: unknown stability %q (expected one of %s)", p.Name, v,
+				strings.Join(cfg.StabilityLevels, ", "))
+		}
+	}
+	return nil
+}
+
+// booleanLike reports the boolean a legacy value stands for: the strings
+// "true"/"false" (any case) or the numbers 1/0.
+func booleanLike(v interface{}) (bool, bool) {
+	switch vv := v.(type) {
+	case string:
+		switch strings.ToLower(strings.TrimSpace(vv)) {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+	default:
+		if f, ok := toFloat(v); ok && (f == 0 || f == 1) {
+			return f == 1, true
+		}
+	}
+	return false, false
+}
+
+// deprecation reports whether p is deprecated and the version it is to be
+// removed in, if the modifier names one ("deprecated:removed-in=2.0.0").
+func deprecation(p *Parameter, cfg *Config) (removedIn string, deprecated bool, err error) {
+	if p.HasModifier(cfg.Modifiers.Deprecated) {
+		return "", true, nil
+	}
+	v, ok := p.ModifierValue(cfg.Modifiers.Deprecated)
+	if !ok {
+		return "", false, nil
+	}
+	version, found := strings.CutPrefix(v, "removed-in=")
+	if !found || strings.TrimSpace(version) == "" {
+		return "", true, fmt.Errorf("%s: invalid %s modifier %q (expected %s:removed-in=<version>)",
+			p.Name, cfg.Modifiers.Deprecated, v, cfg.Modifiers.Deprecated)
+	}
+	return strings.TrimSpace(version), true, nil
+}
+
+// resolveTypeConflict reports whether a type modifier should be applied to p,
+// following cfg.TypeConflict when the actual value has a different type.
+// Values that are unset (nil) or unknown never conflict. When the value is
+// kept, rather than replaced by the empty value of modType, the modifier
+// cannot win: the schema would reject its own default, so the value's type
+// is kept with a warning.
+func resolveTypeConflict(p *Parameter, modifier, modType string, keep bool, cfg *Config) (bool, error) {
+	if p.Type == "" || p.Type == "nil" || p.Type == modType {
+		return true, nil
+	}
+	switch cfg.TypeConflict {
+	case typeConflictValueWins:
+		return false, nil
+	case typeConflictError:
+		return false, fmt.Errorf("type conflict for %s: value is %s but modifier %q implies %s",
+			p.Name, p.Type, modifier, modType)
+	default:
+		if keep {
+			console.WarnAtOnce(p.File, p.Line, "type conflict for %s: value is %s but modifier %q implies %s; "+
+				"keeping %s so that the default validates", p.Name, p.Type, modifier, modType, p.Type)
+			return false, nil
+		}
+		return true, nil
+	}
+}
+
+// sortSectionParameters orders the rows of one section as configured by
+// readme.paramSort. The sort is stable, so a name that is documented twice
+// keeps its file order.
+func sortSectionParameters(params []*Parameter, cfg *Config) {
+	if cfg.Readme.ParamSort != paramSortAlpha {
+		return
+	}
+	sort.SliceStable(params, func(i, j int) bool { return params[i].DocName() < params[j].DocName() })
+}
+
+// buildParamsToRender drops skipped parameters and applies modifiers. Every
+// modifier error is reported; the parameters are returned regardless.
+func buildParamsToRender(list []*Parameter, cfg *Config) ([]*Parameter, error) {
+	out := []*Parameter{}
+	var errs []error
+	for _, p := range list {
+		if p.Skip() {
+			continue
+		}
+		if err := applyModifiers(p, cfg); err != nil {
+			errs = append(errs, err)
+		}
+		out = append(out, p)
+	}
+	return out, errors.Join(errs...)
+}
+
+//-------------------------------------------------------------------------
+// Rendering helpers
+//-------------------------------------------------------------------------
+
+// htmlEscaper only touches angle brackets so entities typed by the author
+// (e.g. &nbsp;) keep working.
+var htmlEscaper = strings.NewReplacer("<", "&lt;", ">", "&gt;")
+
+// tableColumns returns readme.columns, with the Section column of compact
+// tables first.
+func tableColumns(cfg *Config) []string {
+	cols := slices.Clone(cfg.Readme.Columns)
+	if cfg.Readme.Compact {
+		cols = slices.Insert(cols, 0, columnSection)
+	}
+	return cols
+}
+
+func markdownTable(params []*Parameter, cfg *Config) string {
+	labels := map[string]string{
+		columnSection:     cfg.Readme.Headers.Section,
+		columnName:        cfg.Readme.Headers.Name,
+		columnType:        cfg.Readme.Headers.Type,
+		columnRequired:    cfg.Readme.Headers.Required,
+		columnDescription: cfg.Readme.Headers.Description,
+		columnValue:       cfg.Readme.Headers.Value,
+	}
+	columns := tableColumns(cfg)
+	var header []string
+	for _, c := range columns {
+		header = append(header, labels[c])
+	}
+	rows := [][]string{header}
+	var details, examples strings.Builder
+
+	for _, p := range params {
+		val := ""
+		if !p.Extra() {
+			switch vv := p.Value.(type) {
+			case string:
+				if vv == "" {
+					val = "`\"\"`"
+				} else {
+					val = fmt.Sprintf("`%s`", vv)
+				}
+			case int:
+				val = fmt.Sprintf("`%s`", groupDigits(int64(vv), cfg.Readme.DigitSeparator))
+			case int64:
+				val = fmt.Sprintf("`%s`", groupDigits(vv, cfg.Readme.DigitSeparator))
+			default:
+				b, _ := json.Marshal(vv)
+				val = fmt.Sprintf("`%s`", string(b))
+				if summary := summarizeValue(vv); cfg.Readme.SummarizeComplexValues && summary != "" {
+					val = fmt.Sprintf("`%s`", summary)
+					full, _ := json.MarshalIndent(vv, "", "  ")
+					fmt.Fprintf(&details, "\n<details>\n<summary><code>%s</code></summary>\n\n```json\n%s\n```\n\n</details>\n",
+						p.DocName(), full)
+				}
+			}
+		}
+		if p.Source != "" {
+			val = fmt.Sprintf("`%s`", p.Source)
+		}
+		if p.Literal != "" {
+			val = fmt.Sprintf("`%v` (written as `%s`)", p.Value, p.Literal)
+		}
+		if t := inferType(p.Value); p.HasModifier(cfg.Modifiers.Percentage) && (t == "integer" || t == "number") {
+			val = fmt.Sprintf("`%v%%`", p.Value)
+		}
+		if ref, ok := p.ModifierValue(cfg.Modifiers.DefaultRef); ok {
+			val = fmt.Sprintf("defaults to the value of `%s`", ref)
+		}
+		if p.Value == nil && p.HasModifier(cfg.Modifiers.Required) && cfg.Readme.RequiredPlaceholder != "" {
+			val = fmt.Sprintf("`%s`", cfg.Readme.RequiredPlaceholder)
+		}
+		if p.DisplayValue != "" {
+			val = fmt.Sprintf("`%s`", p.DisplayValue)
+		}
+		if content, ok := p.Value.(string); ok && content != "" && p.HasModifierPrefix(cfg.Modifiers.FromFile) {
+			val = fmt.Sprintf("`%s`", inlineText(content, cfg.Readme.FileDefaultMaxLength))
+		}
+		// Values are always inside a code span, where HTML is never
+		// interpreted, so only the description needs escaping.
+		desc := p.Description
+		if cfg.Readme.EscapeHTML {
+			desc = htmlEscaper.Replace(desc)
+		}
+		if removedIn, deprecated, _ := deprecation(p, cfg); deprecated {
+			note := "Deprecated."
+			if removedIn != "" {
+				note = fmt.Sprintf("Deprecated; will be removed in %s.", removedIn)
+			}
+			desc = strings.TrimSpace(note + " " + desc)
+		}
+		if level, ok := p.ModifierValue(cfg.Modifiers.Stability); ok {
+			desc = strings.TrimSpace(fmt.Sprintf("%s (%s)", desc, level))
+		}
+		if constraint, ok := p.ModifierValue(cfg.Modifiers.AllowedVersions); ok {
+			desc = strings.TrimSpace(desc)
+			if desc != "" && !strings.HasSuffix(desc, ".") {
+				desc += "."
+			}
+			desc = strings.TrimSpace(fmt.Sprintf("%s Supported versions: `%s`.", desc, constraint))
+		}
+		if p.HasModifier(cfg.Modifiers.Template) && cfg.Readme.TemplateNote != "" {
+			desc = strings.TrimSpace(desc)
+			if desc != "" && !strings.HasSuffix(desc, ".") {
+				desc += "."
+			}
+			desc = strings.TrimSpace(desc + " " + cfg.Readme.TemplateNote)
+		}
+		mark := ""
+		if p.HasModifier(cfg.Modifiers.Required) {
+			mark = "✓"
+		}
+		name := fmt.Sprintf("`%s`", p.DocName())
+		if cfg.Readme.RowAnchors {
+			name = fmt.Sprintf("<a id=\"%s\"></a>%s", rowAnchor(p.DocName(), cfg), name)
+		}
+		cells := map[string]string{
+			columnSection:     p.Section,
+			columnName:        name,
+			columnType:        strings.ReplaceAll(p.Type, "|", `\|`),
+			columnRequired:    mark,
+			columnDescription: desc,
+			columnValue:       val,
+		}
+		var row []string
+		for _, c := range columns {
+			row = append(row, cells[c])
+		}
+		rows = append(rows, row)
+		if code, ok := p.ModifierValue(cfg.Modifiers.ExampleCode); ok && code != "" {
+			fmt.Fprintf(&examples, "\nExample for `%s`:\n\n```\n%s\n```\n",
+				p.DocName(), strings.ReplaceAll(code, `\n`, "\n"))
+		}
+	}
+
+	// Compact tables keep zero widths: no padding, "---" separators.
+	w := make([]int, len(rows[0]))
+	for _, r := range rows {
+		for i, c := range r {
+			if l := utf8.RuneCountInString(c); l > w[i] && cfg.Readme.TableStyle != tableStyleCompact {
+				w[i] = l
+			}
+		}
+	}
+
+	var b strings.Builder
+	writeTableRow(&b, rows[0], w)
+	writeTableSeparator(&b, w)
+	for i, r := range rows[1:] {
+		// Start a new table, with the same widths, every RowsPerTable rows.
+		if n := cfg.Readme.RowsPerTable; n > 0 && i > 0 && i%n == 0 {
+			b.WriteString("\n")
+			writeTableRow(&b, rows[0], w)
+			writeTableSeparator(&b, w)
+		}
+		writeTableRow(&b, r, w)
+	}
+	b.WriteString(details.String())
+	b.WriteString(examples.String())
+	return b.String()
+}
+
+// groupDigits formats n with sep between groups of three digits once it has
+// five digits or more, so that ports such as 8080 stay as written.
+func groupDigits(n int64, sep string) string {
+	s := strconv.FormatInt(n, 10)
+	digits := strings.TrimPrefix(s, "-")
+	if sep == "" || len(digits) < 5 {
+		return s
+	}
+	var b strings.Builder
+	b.WriteString(s[:len(s)-len(digits)])
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
+
+func writeTableRow(b *strings.Builder, r []string, w []int) {
+	b.WriteString("|")
+	for j, c := range r {
+		b.WriteString(" ")
+		b.WriteString(c)
+		if pad := w[j] - utf8.RuneCountInString(c); pad > 0 {
+			b.WriteString(strings.Repeat(" ", pad))
+		}
+		b.WriteString(" |")
+	}
+	b.WriteString("\n")
+}
+
+func writeTableSeparator(b *strings.Builder, w []int) {
+	b.WriteString("|")
+	for _, ww := range w {
+		if ww == 0 {
+			ww = 3
+		}
+		b.WriteString(" ")
+		b.WriteString(strings.Repeat("-", ww))
+		b.WriteString(" |")
+	}
+	b.WriteString("\n")
+}
+
+// inlineText makes multi-line text fit a table cell: newlines are shown as
+// "\n" and the result is cut to max characters (0 = no limit).
+func inlineText(s string, max int) string {
+	s = strings.ReplaceAll(strings.TrimRight(s, "\n"), "\n", `\n`)
+	if r := []rune(s); max > 0 && len(r) > max {
+		return string(r[:max]) + "…"
+	}
+	return s
+}
+
+// summarizeValue returns "{n keys}" / "[n items]" for non-empty objects and
+// arrays, and "" for everything else.
+func summarizeValue(v interface{}) string {
+	plural := func(n int, word string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", word)
+		}
+		return fmt.Sprintf("%d %ss", n, word)
+	}
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if len(vv) > 0 {
+			return "{" + plural(len(vv), "key") + "}"
+		}
+	case []interface{}:
+		if len(vv) > 0 {
+			return "[" + plural(len(vv), "item") + "]"
+		}
+	}
+	return ""
+}
+
+// renderSection renders one section: its heading, description and table,
+// separated by blank lines. A parent section, one followed by subsections,
+// is not empty and gets no readme.emptySection placeholder.
+func renderSection(sec *Section, h string, parent bool, cfg *Config) string {
+	var blocks []string
+	if cfg.Readme.AnchorPrefix != "" {
+		// GitHub keeps the heading's own anchor too; links should use this
+		// namespaced one.
+		blocks = append(blocks, fmt.Sprintf("<a id=\"%s%s\"></a>\n", cfg.Readme.AnchorPrefix, slugify(sec.Name)))
+	}
+	blocks = append(blocks, fmt.Sprintf("%s %s\n", h, sec.Name))
+
+	if d := sec.Description(); d != "" && cfg.Readme.SectionSummary {
+		blocks = append(blocks, fmt.Sprintf("*%s*\n", firstSentence(d)))
+	}
+	if d := sec.Description(); d != "" {
+		blocks = append(blocks, d+"\n")
+	}
+
+	if len(sec.Parameters) > 0 {
+		blocks = append(blocks, markdownTable(sec.Parameters, cfg))
+	} else if cfg.Readme.EmptySection != "" && !parent {
+		blocks = append(blocks, cfg.Readme.EmptySection+"\n")
+	}
+	if len(sec.Skipped) > 0 {
+		var b strings.Builder
+		for _, p := range sec.Skipped {
+			// "--" may not appear inside an HTML comment; a single pass
+			// would leave one in "---".
+			reason := p.SkipReason
+			for strings.Contains(reason, "--") {
+				reason = strings.ReplaceAll(reason, "--", "- -")
+			}
+			b.WriteString(fmt.Sprintf("<!-- skipped %s: %s -->\n", p.Name, reason))
+		}
+		blocks = append(blocks, b.String())
+	}
+	return strings.Join(blocks, "\n") + renderExamples(sec.Parameters, h+"#")
+}
+
+// renderExamples renders the @example blocks of params, each under a heading
+// h naming its parameter.
+func renderExamples(params []*Parameter, h string) string {
+	if len(h) > 6 {
+		h = "######"
+	}
+	var b strings.Builder
+	for _, p := range params {
+		if len(p.ExampleLines) == 0 {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("\n%s `%s`\n\n```yaml\n%s\n```\n", h, p.DocName(), strings.Join(p.ExampleLines, "\n")))
+	}
+	return b.String()
+}
+
+// firstSentence returns text, with its line breaks collapsed, up to the
+// first '.', '!' or '?' that ends a word; all of it when there is none.
+func firstSentence(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	for i, r := range text {
+		if (r == '.' || r == '!' || r == '?') && (i+1 == len(text) || text[i+1] == ' ') {
+			return text[:i+1]
+		}
+	}
+	return text
+}
+
+func renderReadmeTable(secs []*Section, h string, cfg *Config, cache *renderCache) string {
+	var b strings.Builder
+	if cfg.Readme.Compact {
+		// One flat table; the section becomes a column.
+		var params []*Parameter
+		for _, s := range secs {
+			params = append(params, s.Parameters...)
+		}
+		b.WriteString("\n")
+		table := markdownTable(params, cfg)
+		checkTableWidth("the parameters table", table, cfg)
+		b.WriteString(table)
+		b.WriteString(renderExamples(params, h))
+		return b.String()
+	}
+	for i, s := range secs {
+		b.WriteString("\n")
+		// Nested sections get one more '#' per level; Markdown has six.
+		heading := h + strings.Repeat("#", s.Level)
+		if len(heading) > 6 {
+			heading = "######"
+		}
+		parent := i+1 < len(secs) && secs[i+1].Level > s.Level
+		out := cache.section(s, heading, parent, cfg)
+		checkTableWidth(fmt.Sprintf("section %q", s.Name), out, cfg)
+		b.WriteString(out)
+	}
+	return b.String()
+}
+
+// checkTableWidth warns when the widest table row in md exceeds
+// readme.maxTableWidth. Rows are padded to equal width, so the widest row is
+// the table's width. Rendered output is measured so cached sections are
+// checked too.
+func checkTableWidth(what, md string, cfg *Config) {
+	limit := cfg.Readme.MaxTableWidth
+	if limit <= 0 {
+		return
+	}
+	widest := 0
+	for _, l := range strings.Split(md, "\n") {
+		if n := utf8.RuneCountInString(l); strings.HasPrefix(l, "|") && n > widest {
+			widest = n
+		}
+	}
+	if widest > limit {
+		console.Warn("%s is %d characters wide (limit %d)", what, widest, limit)
+	}
+}
+
+//-------------------------------------------------------------------------
+// Rendered section cache (--cache)
+//-------------------------------------------------------------------------
+
+// renderCache keeps the Markdown of each section keyed by a hash of
+// everything it is rendered from. Key identifies the generator version and
+// configuration; entries written under a different key are discarded.
+type renderCache struct {
+	Key      string            `json:"key"`
+	Sections map[string]string `json:"sections"`
+
+	used map[string]string
+	hits int
+}
+
+// loadRenderCache reads the cache at path. A missing, unreadable or stale
+// cache simply starts empty.
+func loadRenderCache(path string, cfg *Config) *renderCache {
+	raw, _ := json.Marshal(cfg)
+	sum := sha256.Sum256(append([]byte(buildID()+"\n"), raw...))
+	key := hex.EncodeToString(sum[:])
+
+	c := &renderCache{}
+	if data, err := ioutil.ReadFile(path); err == nil {
+		if json.Unmarshal(data, c) != nil || c.Key != key {
+			console.Debug("cache %s is stale, rebuilding", path)
+			c.Sections = nil
+		}
+	}
+	c.Key = key
+	if c.Sections == nil {
+		c.Sections = map[string]string{}
+	}
+	c.used = map[string]string{}
+	return c
+}
+
+// buildID identifies the build for the render cache: the release version, or
+// for "dev" builds a hash of the executable, since any local rebuild may
+// render differently. When the executable cannot be read, every run gets its
+// own ID, so the cache is never reused.
+var buildID = sync.OnceValue(func() string {
+	if Version != "dev" {
+		return Version
+	}
+	if exe, err := os.Executable(); err == nil {
+		if data, err := ioutil.ReadFile(exe); err == nil {
+			sum := sha256.Sum256(data)
+			return "dev-" + hex.EncodeToString(sum[:])
+		}
+	}
+	return "dev-" + time.Now().String()
+})
+
+// sectionKey hashes the heading level, name, description and every field of
+// a parameter that markdownTable or renderExamples reads; a field added to
+// the rendering must be added here too. Source order is left out so that
+// adding a parameter elsewhere does not invalidate this section.
+func sectionKey(sec *Section, h string, parent bool) string {
+	type row struct {
+		Name        string      `json:"name"`
+		Description string      `json:"description"`
+		Value       interface{} `json:"value"`
+		Type        string      `json:"type"`
+		Modifiers   []string    `json:"modifiers"`
+		Section     string      `json:"section"`
+		Literal     string      `json:"literal"`
+		Display     string      `json:"display"`
+		Source      string      `json:"source"`
+		Example     []string    `json:"example"`
+		Extra       bool        `json:"extra"`
+	}
+	rows := make([]row, 0, len(sec.Parameters))
+	for _, p := range sec.Parameters {
+		rows = append(rows, row{p.DocName(), p.Description, p.Value, p.Type, p.Modifiers, p.Section, p.Literal, p.DisplayValue, p.Source, p.ExampleLines,
+			p.Extra()})
+	}
+	skipped := make([]string, 0, len(sec.Skipped))
+	for _, p := range sec.Skipped {
+		skipped = append(skipped, p.Name+": "+p.SkipReason)
+	}
+	raw, _ := json.Marshal(struct {
+		Heading     string   `json:"heading"`
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		Rows        []row    `json:"rows"`
+		Skipped     []string `json:"skipped"`
+		Parent      bool     `json:"parent"`
+	}{h, sec.Name, sec.Description(), rows, skipped, parent})
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// section returns the cached Markdown for sec, rendering it on a miss. A nil
+// cache always renders.
+func (c *renderCache) section(sec *Section, h string, parent bool, cfg *Config) string {
+	if c == nil {
+		return renderSection(sec, h, parent, cfg)
+	}
+	k := sectionKey(sec, h, parent)
+	out, ok := c.Sections[k]
+	if ok {
+		c.hits++
+	} else {
+		out = renderSection(sec, h, parent, cfg)
+	}
+	c.used[k] = out
+	return out
+}
+
+// save writes the entries used in this run, dropping sections that no
+// longer exist.
+func (c *renderCache) save(path string, mode os.FileMode) error {
+	console.Debug("cache: %d of %d section(s) reused", c.hits, len(c.used))
+	c.Sections = c.used
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data, mode)
+}
+
+// insertReadmeTable – replaces existing Parameters section or appends it.
+// The updated README is returned rather than written, so that warnings raised
+// while rendering are seen before anything is written.
+func insertReadmeTable(raw []byte, sections []*Section, cfg *Config, cache *renderCache) ([]byte, error) {
+	// Work on "\n" endings and restore the file's dominant ending at the
+	// end, so a CRLF README does not end up with mixed endings.
+	text := string(raw)
+	crlf := strings.Count(text, "\r\n")
+	useCRLF := crlf > strings.Count(text, "\n")-crlf
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	// Find start of parameters section (level ##+ heading matching cfg.Regexp.ParamsSectionTitle)
+	// The heading may be indented or quoted (inside a list item or a
+	// blockquote); the generated lines then get the same lead.
+	start := -1
+	hPrefix := "##" // default – overwritten when we detect exact hashes
+	lead := ""
+	reStart := regexp.MustCompile(fmt.Sprintf(`^([ \t>]*?)(##+) %s`, cfg.Regexp.ParamsSectionTitle))
+	for i, l := range lines {
+		if m := reStart.FindStringSubmatch(l); m != nil {
+			start = i + 1 // insert after header line
+			lead = m[1]
+			hPrefix = m[2] + "#" // child headings get one more '#'
+			break
+		}
+	}
+
+	if start == -1 {
+		return nil, errors.New("could not find Parameters section in README")
+	}
+
+	// Find end = next header of same level or EOF
+	// (or the first line that leaves the list item / blockquote).
+	end := len(lines)
+	sameLevel := regexp.MustCompile(fmt.Sprintf(`^%s\s`, strings.Repeat("#", len(hPrefix)-1)))
+	for i := start; i < len(lines); i++ {
+		l := lines[i]
+		blank := strings.TrimSpace(l) == "" || strings.TrimSpace(l) == strings.TrimSpace(lead)
+		if !blank && !strings.HasPrefix(l, lead) {
+			// Keep the blank lines separating the outer content.
+			for end = i; end > start && strings.TrimSpace(lines[end-1]) == ""; end-- {
+			}
+			break
+		}
+		if sameLevel.MatchString(strings.TrimPrefix(l, lead)) {
+			end = i
+			break
+		}
+	}
+
+	// Trim trailing existing table lines (just replicate JS logic quickly)
+	// For simplicity we remove everything between start and end and insert fresh.
+	newTable := renderReadmeTable(sections, hPrefix, cfg, cache)
+	newLines := append([]string{}, lines[:start]...)
+	tableLines := strings.Split(newTable, "\n")
+	if end < len(lines) && strings.TrimSpace(lines[end]) == "" {
+		tableLines = tableLines[:len(tableLines)-1]
+	}
+	for _, l := range tableLines {
+		if lead != "" {
+			l = strings.TrimRight(lead+l, " \t")
+		}
+		newLines = append(newLines, l)
+	}
+	newLines = append(newLines, lines[end:]...)
+
+	eol := "\n"
+	if useCRLF {
+		eol = "\r\n"
+	}
+	return []byte(strings.Join(newLines, eol)), nil
+}
+
+//-------------------------------------------------------------------------
+// OpenAPI Schema – minimal implementation (object graph with default values)
+//-------------------------------------------------------------------------
+
+// SchemaObject is a node of the generated schema, marshalled as-is.
+type SchemaObject map[string]interface{}
+
+type schemaGenerator struct {
+	root     SchemaObject
+	rootType string
+	cfg      *Config
+	// tuples collects, per array node, the schemas of parameters that
+	// document a single position such as range[1].
+	tuples map[string]*tupleSchema
+}
+
+// tupleSchema is an array node and the schemas of its documented positions.
+type tupleSchema struct {
+	node  SchemaObject
+	items map[int]SchemaObject
+}
+
+// newSchemaGenerator starts a schema whose root has type rootType. Only an
+// object root has properties and only an array root has items.
+func newSchemaGenerator(rootType string, cfg *Config) *schemaGenerator {
+	root := SchemaObject{"title": "Chart Values", "type": rootType}
+	switch rootType {
+	case "object":
+		root["properties"] = SchemaObject{}
+	case "array":
+		root["items"] = SchemaObject{}
+	}
+	if cfg.Schema.ID != "" {
+		root["$id"] = cfg.Schema.ID
+	}
+	return &schemaGenerator{root: root, rootType: rootType, cfg: cfg, tuples: map[string]*tupleSchema{}}
+}
+
+func (s *schemaGenerator) add(param *Parameter) {
+	keyPattern, hasKeyPattern := param.ModifierValue(s.cfg.Modifiers.PropertyNames)
+	if param.Extra() || !param.Schema {
+		return
+	}
+
+	// The default is a copy, so that editing one node's default never
+	// changes the values or another node sharing the same map or slice.
+	obj := SchemaObject{
+		"type":        param.Type,
+		"description": param.Description,
+		"default":     deepCopyValue(param.Value),
+	}
+	if param.DisplayValue != "" {
+		obj["default"] = param.DisplayValue
+	} else if param.Value == nil && param.HasModifier(s.cfg.Modifiers.Required) {
+		// An unset required value has no default.
+		delete(obj, "default")
+	}
+	if ref, ok := param.ModifierValue(s.cfg.Modifiers.DefaultRef); ok {
+		desc := strings.TrimSpace(param.Description)
+		if desc != "" && !strings.HasSuffix(desc, ".") {
+			desc += "."
+		}
+		obj["description"] = strings.TrimSpace(fmt.Sprintf("%s Defaults to the value of %s.", desc, ref))
+	}
+	obj["description"] = normalizeDescription(obj["description"].(string), s.cfg)
+	if param.Type == "" {
+		// A <placeholder> key of a map with no entries has no value to
+		// take its type from.
+		delete(obj, "type")
+	}
+	if types := strings.Split(param.Type, "|"); len(types) > 1 {
+		if s.cfg.Schema.Dialect == schemaDialectDraft07 {
+			obj["type"] = types
+		} else {
+			// OpenAPI 3.0 has no "null" type: a single other type becomes
+			// nullable, several get an alternative that only admits null.
+			nonNull := slices.DeleteFunc(slices.Clone(types), func(t string) bool { return t == "null" })
+			if len(nonNull) == 1 {
+				obj["type"] = nonNull[0]
+				obj["nullable"] = true
+			} else {
+				delete(obj, "type")
+				var alts []SchemaObject
+				for _, t := range types {
+					if t == "null" {
+						alts = append(alts, SchemaObject{"enum": []interface{}{nil}})
+						continue
+					}
+					alts = append(alts, SchemaObject{"type": t})
+				}
+				obj["oneOf"] = alts
+			}
+		}
+	}
+	if param.HasModifier(s.cfg.Modifiers.Nullable) {
+		obj["nullable"] = true
+	}
+	if param.HasModifier(s.cfg.Modifiers.Duration) {
+		obj["pattern"] = s.cfg.Patterns.Duration
+	}
+	if param.HasModifier(s.cfg.Modifiers.ByteSize) {
+		obj["pattern"] = s.cfg.Patterns.ByteSize
+	}
+	if param.HasModifier(s.cfg.Modifiers.DNSLabel) {
+		obj["pattern"] = s.cfg.Patterns.DNSLabel
+		obj["maxLength"] = dnsLabelMaxLength
+	}
+	if param.HasModifier(s.cfg.Modifiers.DNSSubdomain) {
+		obj["pattern"] = s.cfg.Patterns.DNSSubdomain
+		obj["maxLength"] = dnsSubdomainMaxLength
+	}
+	if pattern, ok := param.ModifierValue(s.cfg.Modifiers.Pattern); ok {
+		obj["pattern"] = pattern
+	}
+	if msg, ok := param.ModifierValue(s.cfg.Modifiers.ErrorMessage); ok {
+		obj["errorMessage"] = msg
+	}
+	if len(param.Enum) > 0 {
+		enum := typedEnum(param.Enum, param.Type)
+		if param.HasModifier(s.cfg.Modifiers.Nullable) {
+			// OpenAPI 3.0 only accepts null for a nullable enum when listed.
+			enum = append(enum, nil)
+		}
+		obj["enum"] = enum
+	}
+	if param.HasModifier(s.cfg.Modifiers.Percentage) {
+		obj["minimum"] = 0
+		obj["maximum"] = 100
+	}
+	if param.HasModifier(s.cfg.Modifiers.Template) {
+		obj["x-helm-template"] = true
+	}
+	if param.HasModifier(s.cfg.Modifiers.Redact) {
+		obj["x-redact"] = true
+	}
+	if param.HasModifier(s.cfg.Modifiers.Password) {
+		// Formats only apply to strings.
+		if param.Type == "string" {
+			obj["format"] = "password"
+		}
+		obj["writeOnly"] = true
+	}
+	if removedIn, deprecated, _ := deprecation(param, s.cfg); deprecated {
+		obj["deprecated"] = true
+		if removedIn != "" {
+			obj["x-removed-in"] = removedIn
+		}
+	}
+	if level, ok := param.ModifierValue(s.cfg.Modifiers.Stability); ok {
+		obj["x-stability"] = level
+	}
+	if constraint, ok := param.ModifierValue(s.cfg.Modifiers.AllowedVersions); ok {
+		obj["x-allowed-versions"] = constraint
+	}
+	if s.cfg.Schema.Sections && param.Section != "" {
+		obj["x-section"] = param.Section
+	}
+	if hasKeyPattern && param.Type == "object" {
+		obj["propertyNames"] = SchemaObject{"pattern": keyPattern}
+	}
+	if param.Type == "array" {
+		arr, _ := param.Value.([]interface{})
+		obj["items"] = itemsSchema(arr)
+	}
+	if def, ok := param.ModifierValue(s.cfg.Modifiers.K8sType); ok {
+		// The Kubernetes definition describes the value; only the
+		// annotations are kept next to the reference.
+		for _, k := range []string{"type", "oneOf", "items", "propertyNames"} {
+			delete(obj, k)
+		}
+		obj["$ref"] = s.cfg.Schema.KubernetesDefinitions + "#/definitions/" + def
+	}
+
+	// Walk (and create) the intermediate nodes: names descend into
+	// "properties", indexes into "items", so a[0][1].b nests correctly.
+	segs := pathSegments(param.Name)
+	index := strings.HasPrefix(segs[0], "[")
+	if fits := s.rootType == "object" && !index || s.rootType == "array" && index; !fits {
+		console.Warn("parameter %s does not fit the %s schema root; skipped in the schema", param.Name, s.rootType)
+		return
+	}
+	node, parent := s.root, s.root
+	inItems := false
+	for _, seg := range segs {
+		parent, node = node, schemaChild(node, seg)
+		inItems = inItems || strings.HasPrefix(seg, "[")
+	}
+	if param.HasModifier(s.cfg.Modifiers.Required) {
+		addRequired(parent, segs[len(segs)-1])
+	}
+	// Positions of an array inside another array's items stay shared, as
+	// the elements of matrix[0] and matrix[1] are described together.
+	if last := segs[len(segs)-1]; strings.HasPrefix(last, "[") && !slices.ContainsFunc(segs[:len(segs)-1], func(seg string) bool {
+		return strings.HasPrefix(seg, "[")
+	}) {
+		if i, err := strconv.Atoi(strings.Trim(last, "[]")); err == nil {
+			key := fmt.Sprintf("%p", parent)
+			if s.tuples[key] == nil {
+				s.tuples[key] = &tupleSchema{node: parent, items: map[int]SchemaObject{}}
+			}
+			s.tuples[key].items[i] = obj
+		}
+	}
+	for k, v := range obj {
+		// All indexes share one items schema; the first documented element
+		// (e.g. containers[0].name over containers[1].name) describes it.
+		if _, set := node[k]; set && inItems {
+			continue
+		}
+		node[k] = v
+	}
+}
+
+// isPlaceholder reports whether a path segment is a "<name>" placeholder for
+// any key of a map.
+func isPlaceholder(seg string) bool {
+	return len(seg) > 2 && strings.HasPrefix(seg, "<") && strings.HasSuffix(seg, ">")
+}
+
+// placeholderPattern returns a regexp matching the keys documented by a name
+// with placeholder segments (extraVolumes.<name>.mountPath), or nil when the
+// name has none.
+func placeholderPattern(name string) *regexp.Regexp {
+	segs := pathSegments(name)
+	found := false
+	var b strings.Builder
+	for i, seg := range segs {
+		if i > 0 && !strings.HasPrefix(seg, "[") {
+			b.WriteString(`\.`)
+		}
+		if isPlaceholder(seg) {
+			b.WriteString(`[^.\[\]]+`)
+			found = true
+			continue
+		}
+		b.WriteString(regexp.QuoteMeta(seg))
+	}
+	if !found {
+		return nil
+	}
+	return regexp.MustCompile("^" + b.String() + "$")
+}
+
+// addRequired lists the property seg in the "required" array of the object
+// node. Index and placeholder segments name no property and are ignored.
+func addRequired(node SchemaObject, seg string) {
+	if strings.HasPrefix(seg, "[") || isPlaceholder(seg) {
+		return
+	}
+	required, _ := node["required"].([]string)
+	for _, name := range required {
+		if name == seg {
+			return
+		}
+	}
+	node["required"] = append(required, seg)
+}
+
+// pathSegments splits a flattened key such as "a.b[0][1].c" into property
+// names and index segments: ["a" "b" "[0]" "[1]" "c"].
+func pathSegments(name string) []string {
+	var segs []string
+	for _, part := range strings.Split(name, ".") {
+		if idx := strings.Index(part, "["); idx > 0 {
+			segs = append(segs, part[:idx])
+			part = part[idx:]
+		}
+		for strings.HasPrefix(part, "[") {
+			end := strings.Index(part, "]")
+			if end == -1 {
+				break
+			}
+			segs = append(segs, part[:end+1])
+			part = part[end+1:]
+		}
+		if part != "" {
+			segs = append(segs, part)
+		}
+	}
+	return segs
+}
+
+// schemaChild returns the node for seg below node, creating it when needed.
+// All indexes of an array share its single "items" schema.
+func schemaChild(node SchemaObject, seg string) SchemaObject {
+	if strings.HasPrefix(seg, "[") {
+		node["type"] = "array"
+		items, ok := node["items"].(SchemaObject)
+		if !ok {
+			items = SchemaObject{}
+			node["items"] = items
+		}
+		return items
+	}
+	node["type"] = "object"
+	if isPlaceholder(seg) {
+		// Every entry, whatever its key, shares one schema.
+		child, ok := node["additionalProperties"].(SchemaObject)
+		if !ok {
+			child = SchemaObject{}
+			node["additionalProperties"] = child
+		}
+		return child
+	}
+	props, ok := node["properties"].(SchemaObject)
+	if !ok {
+		props = SchemaObject{}
+		node["properties"] = props
+	}
+	child, ok := props[seg].(SchemaObject)
+	if !ok {
+		child = SchemaObject{}
+		props[seg] = child
+	}
+	return child
+}
+
+// deepCopyValue returns a copy of a decoded YAML or JSON value that shares no
+// maps or slices with v.
+func deepCopyValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(vv))
+		for k, c := range vv {
+			out[k] = deepCopyValue(c)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(vv))
+		for i, c := range vv {
+			out[i] = deepCopyValue(c)
+		}
+		return out
+	}
+	return v
+}
+
+// addTuples describes arrays with two or more positions documented on their
+// own, such as range[0] and range[1], position by position. draft-07 gets a
+// tuple "items" list. OpenAPI 3.0 has no tuples: "items" keeps only a type
+// shared by all positions and the positions go to "x-items".
+func (s *schemaGenerator) addTuples() {
+	for _, key := range slices.Sorted(maps.Keys(s.tuples)) {
+		t := s.tuples[key]
+		if len(t.items) < 2 {
+			continue
+		}
+		n := slices.Max(slices.Collect(maps.Keys(t.items))) + 1
+		list := make([]interface{}, n)
+		types := map[string]bool{}
+		for i := range list {
+			item, ok := t.items[i]
+			if !ok {
+				// An undocumented position accepts anything.
+				item = SchemaObject{}
+			}
+			list[i] = item
+			types[fmt.Sprint(item["type"])] = true
+		}
+		if s.cfg.Schema.Dialect == schemaDialectDraft07 {
+			t.node["items"] = list
+			continue
+		}
+		shared := SchemaObject{}
+		if typ, ok := t.items[0]["type"].(string); ok && len(types) == 1 {
+			shared["type"] = typ
+		}
+		t.node["items"] = shared
+		t.node["x-items"] = list
+	}
+}
+
+// typedEnum converts the entries of an enum list to the parameter's type, so
+// that "[enum:1,2]" on an integer lists numbers. Entries that do not parse
+// stay strings.
+func typedEnum(list []string, typ string) []interface{} {
+	out := make([]interface{}, 0, len(list))
+	for _, e := range list {
+		var v interface{} = e
+		switch typ {
+		case "integer":
+			if n, err := strconv.ParseInt(e, 10, 64); err == nil {
+				v = n
+			}
+		case "number":
+			if f, err := strconv.ParseFloat(e, 64); err == nil {
+				v = f
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(e); err == nil {
+				v = b
+			}
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// itemsSchema describes the elements of arr from its first element,
+// recursing into nested arrays.
+func itemsSchema(arr []interface{}) SchemaObject {
+	items := SchemaObject{}
+	if len(arr) == 0 {
+		return items
+	}
+	items["type"] = inferType(arr[0])
+	if inner, ok := arr[0].([]interface{}); ok {
+		items["items"] = itemsSchema(inner)
+	}
+	return items
+}
+
+// addExclusiveGroups constrains every "oneOf-group" so that no two of its
+// members are set (non-null) at the same time. Members must be siblings; the
+// constraint is placed on their parent object.
+func (s *schemaGenerator) addExclusiveGroups(params []*Parameter) error {
+	groups := exclusiveGroups(params, s.cfg)
+	for _, g := range sortedKeys(groups) {
+		parent, names, err := groupParent(groups[g])
+		if err != nil {
+			return err
+		}
+		node := s.root
+		for _, seg := range parent {
+			node = schemaChild(node, seg)
+		}
+		set := SchemaObject{"not": SchemaObject{"type": "null"}}
+		var pairs []interface{}
+		for i := range names {
+			for j := i + 1; j < len(names); j++ {
+				pairs = append(pairs, SchemaObject{
+					"required":   []string{names[i], names[j]},
+					"properties": SchemaObject{names[i]: set, names[j]: set},
+				})
+			}
+		}
+		if len(pairs) == 0 {
+			continue
+		}
+		// Several groups under the same parent are combined with allOf.
+		constraint := SchemaObject{"not": SchemaObject{"anyOf": pairs}}
+		if _, ok := node["not"]; ok {
+			all, _ := node["allOf"].([]interface{})
+			node["allOf"] = append(all, constraint)
+		} else {
+			node["not"] = constraint["not"]
+		}
+	}
+	return nil
+}
+
+// normalizeDescription applies the schema description style: surrounding
+// whitespace is trimmed, then the first letter and trailing period are
+// adjusted as configured. Empty descriptions stay empty.
+func normalizeDescription(desc string, cfg *Config) string {
+	desc = strings.TrimSpace(desc)
+	if desc == "" {
+		return desc
+	}
+	if cfg.Schema.CapitalizeDescriptions {
+		r, size := utf8.DecodeRuneInString(desc)
+		desc = string(unicode.ToUpper(r)) + desc[size:]
+	}
+	switch cfg.Schema.TrailingPeriod {
+	case trailingPeriodStrip:
+		desc = strings.TrimRight(desc, ".")
+	case trailingPeriodAdd:
+		if !strings.HasSuffix(desc, ".") {
+			desc += "."
+		}
+	}
+	return desc
+}
+
+// addConditionalRequired emits an if/then block for every "if-required"
+// parameter: when the referenced flag is true, the parameter is required.
+// The block is added to the allOf of the deepest object containing both keys.
+func (s *schemaGenerator) addConditionalRequired(params []*Parameter) error {
+	known := map[string]bool{}
+	for _, p := range params {
+		known[p.Name] = true
+	}
+	for _, p := range params {
+		flagKey, ok := p.ModifierValue(s.cfg.Modifiers.IfRequired)
+		if !ok {
+			continue
+		}
+		if !known[flagKey] {
+			return fmt.Errorf("%s: if-required references undocumented key %s", p.Name, flagKey)
+		}
+		target, flagSegs := pathSegments(p.Name), pathSegments(flagKey)
+		for _, seg := range append(append([]string{}, target...), flagSegs...) {
+			if strings.HasPrefix(seg, "[") {
+				return fmt.Errorf("%s: if-required only supports object properties", p.Name)
+			}
+		}
+		common := 0
+		for common < len(target)-1 && common < len(flagSegs)-1 && target[common] == flagSegs[common] {
+			common++
+		}
+		node := s.root
+		for _, seg := range target[:common] {
+			node = schemaChild(node, seg)
+		}
+		rel, flagRel := target[common:], flagSegs[common:]
+		all, _ := node["allOf"].([]interface{})
+		node["allOf"] = append(all, SchemaObject{
+			"if":   nestRequired(flagRel, SchemaObject{"const": true}),
+			"then": nestRequired(rel[:len(rel)-1], SchemaObject{"required": []string{rel[len(rel)-1]}}),
+		})
+	}
+	return nil
+}
+
+// nestRequired wraps inner in required object properties along segs, e.g.
+// ["tls", "enabled"] yields {properties: {tls: {properties: {enabled: inner},
+// required: [enabled]}}, required: [tls]}.
+func nestRequired(segs []string, inner SchemaObject) SchemaObject {
+	if len(segs) == 0 {
+		return inner
+	}
+	return SchemaObject{
+		"properties": SchemaObject{segs[0]: nestRequired(segs[1:], inner)},
+		"required":   []string{segs[0]},
+	}
+}
+
+// exclusiveGroups collects the members of each "oneOf-group", keyed by group
+// name, in documentation order.
+func exclusiveGroups(params []*Parameter, cfg *Config) map[string][]*Parameter {
+	groups := map[string][]*Parameter{}
+	for _, p := range params {
+		if g, ok := p.ModifierValue(cfg.Modifiers.OneOfGroup); ok && g != "" {
+			groups[g] = append(groups[g], p)
+		}
+	}
+	return groups
+}
+
+func sortedKeys(groups map[string][]*Parameter) []string {
+	names := make([]string, 0, len(groups))
+	for g := range groups {
+		names = append(names, g)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// groupParent returns the path segments of the object holding every member of
+// group, and the member property names.
+func groupParent(group []*Parameter) ([]string, []string, error) {
+	var parent, names []string
+	for i, p := range group {
+		segs := pathSegments(p.Name)
+		last := segs[len(segs)-1]
+		if strings.HasPrefix(last, "[") {
+			return nil, nil, fmt.Errorf("oneOf-group member %s must be an object property", p.Name)
+		}
+		if i == 0 {
+			parent = segs[:len(segs)-1]
+		} else if strings.Join(segs[:len(segs)-1], ".") != strings.Join(parent, ".") {
+			return nil, nil, fmt.Errorf("oneOf-group members %s and %s do not share a parent", group[0].Name, p.Name)
+		}
+		names = append(names, last)
+	}
+	return parent, names, nil
+}
+
+// buildOpenAPISchema builds the schema of params. The root type is
+// schema.rootType when set, else rootType, else "object".
+func buildOpenAPISchema(params []*Parameter, rootType string, cfg *Config) (SchemaObject, error) {
+	if cfg.Schema.RootType != "" {
+		rootType = cfg.Schema.RootType
+	}
+	if rootType == "" {
+		rootType = "object"
+	}
+	gen := newSchemaGenerator(rootType, cfg)
+	for _, p := range params {
+		gen.add(p)
+	}
+	if err := gen.addExclusiveGroups(params); err != nil {
+		return nil, err
+	}
+	if err := gen.addConditionalRequired(params); err != nil {
+		return nil, err
+	}
+	gen.addTuples()
+	if !cfg.Schema.AdditionalProperties {
+		closeObjects(gen.root)
+	}
+	if cfg.Schema.Deduplicate {
+		deduplicateObjects(gen.root, definitionsKey(cfg))
+	}
+	if props, ok := gen.root["properties"].(SchemaObject); ok && len(props) == 0 {
+		console.Warn("generated schema has no properties (every parameter is skipped or extra)")
+	}
+	return gen.root, nil
+}
+
+// closeObjects sets "additionalProperties": false on node and every object
+// below it with documented keys, so that misspelt keys are rejected. Objects
+// without documented keys, e.g. "[object]" parameters, stay open with
+// "additionalProperties": true; placeholder keys keep their entry schema.
+func closeObjects(node SchemaObject) {
+	if node["type"] == "object" {
+		if _, set := node["additionalProperties"]; !set {
+			props, _ := node["properties"].(SchemaObject)
+			node["additionalProperties"] = len(props) == 0
+		}
+	}
+	forEachSubschema(node, "", func(_ string, child SchemaObject) SchemaObject {
+		closeObjects(child)
+		return child
+	})
+}
+
+// openObjects removes the "additionalProperties": false set by closeObjects
+// from node and everything below it.
+func openObjects(node SchemaObject) {
+	if node["additionalProperties"] == false {
+		delete(node, "additionalProperties")
+	}
+	forEachSubschema(node, "", func(_ string, child SchemaObject) SchemaObject {
+		openObjects(child)
+		return child
+	})
+}
+
+// definitionsKey is the root key that deduplicateObjects hoists objects
+// into. OpenAPI 3.0 has no "definitions" keyword, and a schema object only
+// accepts "x-" extensions besides its own fields, so objects go under
+// "x-definitions" there; a "$ref" may point anywhere in the document.
+func definitionsKey(cfg *Config) string {
+	if cfg.Schema.Dialect == schemaDialectDraft07 {
+		return "definitions"
+	}
+	return "x-definitions"
+}
+
+// deduplicateObjects moves object schemas that occur more than once with
+// identical content, descriptions and defaults included, into the root key
+// defsKey and replaces every occurrence with a "$ref". The outermost
+// repeated object is hoisted whole; repeated objects inside definitions are
+// hoisted in turn. Definitions are named by definitionNames.
+func deduplicateObjects(root SchemaObject, defsKey string) {
+	counts := map[string]int{}
+	keys := map[string][]string{}
+	var count func(node SchemaObject, key string)
+	count = func(node SchemaObject, key string) {
+		forEachSubschema(node, key, func(key string, child SchemaObject) SchemaObject {
+			if props, ok := child["properties"].(SchemaObject); ok && len(props) > 0 {
+				fp := string(schemaJSON(child))
+				counts[fp]++
+				keys[fp] = append(keys[fp], key)
+			}
+			count(child, key)
+			return child
+		})
+	}
+	count(root, "")
+	names := definitionNames(counts, keys)
+
+	type hoisted struct {
+		node SchemaObject
+		key  string
+	}
+	defs := SchemaObject{}
+	var queue []hoisted
+	var replace func(node SchemaObject, key string)
+	replace = func(node SchemaObject, key string) {
+		forEachSubschema(node, key, func(key string, child SchemaObject) SchemaObject {
+			fp := string(schemaJSON(child))
+			if counts[fp] < 2 {
+				replace(child, key)
+				return child
+			}
+			name := names[fp]
+			if defs[name] == nil {
+				defs[name] = child
+				queue = append(queue, hoisted{child, key})
+			}
+			return SchemaObject{"$ref": "#/" + defsKey + "/" + pointerEscaper.Replace(name)}
+		})
+	}
+	replace(root, "")
+	for len(queue) > 0 {
+		h := queue[0]
+		queue = queue[1:]
+		replace(h.node, h.key)
+	}
+	if len(defs) > 0 {
+		root[defsKey] = defs
+		console.Debug("schema: %d repeated object(s) moved to %s", len(defs), defsKey)
+	}
+}
+
+// definitionNames names the definition of every object schema, by its JSON,
+// that occurs more than once. An object found under the same key everywhere,
+// such as "limits", is named after it; otherwise, or when objects of
+// different content share that key, the name is made from a hash of the
+// content, so that no occurrence is named after an unrelated key.
+func definitionNames(counts map[string]int, keys map[string][]string) map[string]string {
+	shared := map[string]string{}
+	claims := map[string]int{}
+	for fp, n := range counts {
+		if n < 2 {
+			continue
+		}
+		if k := keys[fp]; !slices.ContainsFunc(k, func(s string) bool { return s != k[0] }) {
+			shared[fp] = k[0]
+			claims[k[0]]++
+		}
+	}
+	names := map[string]string{}
+	for fp, n := range counts {
+		if n < 2 {
+			continue
+		}
+		sum := sha256.Sum256([]byte(fp))
+		hash := hex.EncodeToString(sum[:4])
+		switch key, ok := shared[fp]; {
+		case ok && claims[key] == 1:
+			names[fp] = key
+		case ok:
+			names[fp] = key + "-" + hash
+		default:
+			names[fp] = "object-" + hash
+		}
+	}
+	return names
+}
+
+// pointerEscaper escapes a key for use in a JSON pointer (RFC 6901).
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// forEachSubschema calls fn, in key order, for every schema describing a
+// value below node: its properties, items and additionalProperties, and the
+// positions of a tuple, listed in "items" or "x-items" (see addTuples). key
+// is the property name, or the parent's key with "Item" appended for
+// elements. The schema fn returns replaces the child.
+func forEachSubschema(node SchemaObject, key string, fn func(key string, child SchemaObject) SchemaObject) {
+	if props, ok := node["properties"].(SchemaObject); ok {
+		for _, k := range sortedMapKeys(props) {
+			if child, ok := props[k].(SchemaObject); ok {
+				props[k] = fn(k, child)
+			}
+		}
+	}
+	for _, k := range []string{"items", "x-items", "additionalProperties"} {
+		switch child := node[k].(type) {
+		case SchemaObject:
+			node[k] = fn(key+"Item", child)
+		case []interface{}:
+			for i, c := range child {
+				if c, ok := c.(SchemaObject); ok {
+					child[i] = fn(key+"Item", c)
+				}
+			}
+		}
+	}
+}
+
+// combineSubchartSchemas adds the schema of every NAME=FILE subchart under
+// "$defs" and references it from the root property NAME, the key Helm passes
+// the subchart's values under. Keys the umbrella chart documents itself are
+// kept next to the reference with allOf, so both apply. Local references
+// inside a subchart schema are rewritten to point into its definition.
+func combineSubchartSchemas(root SchemaObject, specs []string) error {
+	props, ok := root["properties"].(SchemaObject)
+	if !ok {
+		return errors.New("subchart schemas can only be combined into an object schema")
+	}
+	defs := SchemaObject{}
+	for _, spec := range specs {
+		name, path, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || path == "" {
+			return fmt.Errorf("invalid --subchart-schema %q (expected NAME=FILE)", spec)
+		}
+		if _, dup := defs[name]; dup {
+			return fmt.Errorf("subchart %q is given twice", name)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var sub map[string]interface{}
+		if err := json.Unmarshal(data, &sub); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		// Only the root of the combined schema identifies a document.
+		delete(sub, "$schema")
+		delete(sub, "$id")
+		base := "#/$defs/" + name
+		defs[name] = rebaseRefs(sub, base)
+
+		ref := SchemaObject{"$ref": base}
+		if own, ok := props[name].(SchemaObject); ok {
+			// The umbrella chart only documents some of the subchart's
+			// keys; closing its part would reject all the others.
+			openObjects(own)
+			props[name] = SchemaObject{"allOf": []interface{}{ref, own}}
+		} else {
+			props[name] = ref
+		}
+		console.Debug("subchart schema %s from %s", name, path)
+	}
+	root["$defs"] = defs
+	return nil
+}
+
+// rebaseRefs prefixes every local "$ref" ("#/...") in v with base, for a
+// schema moved from the root of its document to base.
+func rebaseRefs(v interface{}, base string) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, c := range vv {
+			if ref, ok := c.(string); ok && k == "$ref" && strings.HasPrefix(ref, "#") {
+				vv[k] = base + strings.TrimPrefix(ref, "#")
+				continue
+			}
+			vv[k] = rebaseRefs(c, base)
+		}
+	case []interface{}:
+		for i, c := range vv {
+			vv[i] = rebaseRefs(c, base)
+		}
+	}
+	return v
+}
+
+func writeOpenAPISchema(path string, schema SchemaObject, mode os.FileMode) error {
+	return writeFile(path, schemaJSON(schema), mode)
+}
+
+// schemaJSON encodes the schema as written to disk. encoding/json emits the
+// keys of every map, nested ones included, in sorted order, so the output is
+// stable across runs; deduplicateObjects relies on it as a fingerprint.
+func schemaJSON(schema SchemaObject) []byte {
+	data, _ := json.MarshalIndent(schema, "", "    ")
+	return data
+}
+
+//-------------------------------------------------------------------------
+// getParsedMetadata combines everything like JS version
+//-------------------------------------------------------------------------
+
+// getParsedMetadata returns a nil Metadata only when the values file cannot be
+// read or parsed. Validation errors are returned together with the combined
+// metadata so that callers may carry on. The metadata comments are read from
+// comments, or from the values files themselves when comments is nil.
+func getParsedMetadata(files, comments []valuesFile, cfg *Config) (*Metadata, error) {
+	valuesObj, root, err := createValuesObject(files, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if comments == nil {
+		comments = files
+	}
+	meta, err := parseMetadataComments(comments, cfg)
+	if err != nil {
+		return nil, err
+	}
+	meta.RootType = rootType(root)
+	valuesObj = excludeParameters(valuesObj, cfg)
+	meta.dropParameters(excludedParameters(meta.Parameters, cfg))
+	keys := checkKeys(valuesObj, meta.Parameters)
+	keysErr := keys.report(cfg)
+	meta.Keys = keys.keyReport()
+	// Metadata for keys that do not exist has no value to document.
+	meta.dropParameters(keys.orphan)
+	checkErr := errors.Join(
+		keysErr,
+		checkDefaultRefs(valuesObj, meta.Parameters, cfg),
+		checkExtraShadowing(valuesObj, meta.Parameters, cfg),
+		checkSectionAnchors(meta.Sections, cfg),
+		checkCommentFormat(comments, cfg),
+		checkSectionPrefixes(meta.Sections, cfg),
+		checkModifierNames(meta.Parameters, cfg),
+	)
+	checkExclusiveGroups(valuesObj, meta.Parameters, cfg)
+	checkRowAnchors(meta.Sections, cfg)
+	combineMetadataAndValues(valuesObj, meta.Parameters)
+	fillContainerValues(meta.Parameters, root)
+	// fromFile paths are relative to the first values file.
+	if err := loadFileDefaults(meta.Parameters, filepath.Dir(files[0].path), cfg); err != nil {
+		return nil, err
+	}
+	return meta, checkErr
+}
+
+// fillContainerValues gives documented objects and arrays the value written
+// in the values files. Only leaves are flattened, so a populated one would
+// otherwise look unset.
+func fillContainerValues(params []*Parameter, root interface{}) {
+	for _, p := range params {
+		if p.Extra() || p.Value != nil {
+			continue
+		}
+		v, ok := lookupValue(root, p.Name)
+		if !ok {
+			continue
+		}
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			p.Value, p.Type = v, inferType(v)
+		}
+	}
+}
+
+// lookupValue returns the value at a flattened key such as "a.b[0].c".
+func lookupValue(root interface{}, name string) (interface{}, bool) {
+	v := root
+	for _, seg := range pathSegments(name) {
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			child, ok := vv[seg]
+			if !ok {
+				return nil, false
+			}
+			v = child
+		case []interface{}:
+			i, err := strconv.Atoi(strings.Trim(seg, "[]"))
+			if err != nil || !strings.HasPrefix(seg, "[") || i < 0 || i >= len(vv) {
+				return nil, false
+			}
+			v = vv[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// loadFileDefaults sets the value of every "fromFile" parameter to the content
+// of the referenced file, resolved relative to dir.
+func loadFileDefaults(params []*Parameter, dir string, cfg *Config) error {
+	for _, p := range params {
+		// An empty path is reported by checkModifierValues.
+		path, ok := p.ModifierValue(cfg.Modifiers.FromFile)
+		if !ok || path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.Name, err)
+		}
+		p.Value = string(data)
+		p.Type = "string"
+	}
+	return nil
+}
+
+// checkDefaultRefs verifies that every "default-ref" points at a key that
+// exists in values.yaml or is documented.
+func checkDefaultRefs(values []*Parameter, meta []*Parameter, cfg *Config) error {
+	known := map[string]bool{}
+	for _, list := range [][]*Parameter{values, meta} {
+		for _, p := range list {
+			known[p.Name] = true
+		}
+	}
+	var dangling bool
+	for _, p := range meta {
+		if ref, ok := p.ModifierValue(cfg.Modifiers.DefaultRef); ok && ref != "" && !known[ref] {
+			console.Error("%s defaults to non existing key: %s", p.Name, ref)
+			dangling = true
+		}
+	}
+	if dangling {
+		return errors.New("dangling default references found")
+	}
+	return nil
+}
+
+// checkExtraShadowing reports @extra parameters documenting a key that is an
+// actual value; intermediate objects are what @extra is for and are allowed.
+func checkExtraShadowing(values []*Parameter, meta []*Parameter, cfg *Config) error {
+	if !cfg.Validation.ExtraShadowing {
+		return nil
+	}
+	real := map[string]bool{}
+	for _, p := range values {
+		real[p.Name] = true
+	}
+	var shadowing bool
+	for _, p := range meta {
+		if p.Extra() && real[p.Name] {
+			console.Error("@extra parameter shadows existing key: %s", p.Name)
+			shadowing = true
+		}
+	}
+	if shadowing {
+		return errors.New("extra parameters shadow real keys")
+	}
+	return nil
+}
+
+// checkSectionAnchors reports sections whose headings map to the same anchor.
+// GitHub would suffix the later ones with -1, -2…, so their links depend on
+// section order.
+func checkSectionAnchors(sections []*Section, cfg *Config) error {
+	if !cfg.Validation.SectionAnchors {
+		return nil
+	}
+	first := map[string]string{}
+	var collide bool
+	for _, sec := range sections {
+		slug := cfg.Readme.AnchorPrefix + slugify(sec.Name)
+		if prev, ok := first[slug]; ok {
+			console.Error("sections %q and %q share the anchor #%s", prev, sec.Name, slug)
+			collide = true
+			continue
+		}
+		first[slug] = sec.Name
+	}
+	if collide {
+		return errors.New("colliding section anchors found")
+	}
+	return nil
+}
+
+// rowAnchor returns the anchor of a parameter's row: the prefix and the slug
+// of its name with dots read as spaces, e.g. "params-image-tag".
+func rowAnchor(name string, cfg *Config) string {
+	return cfg.Readme.AnchorPrefix + slugify(strings.ReplaceAll(name, ".", " "))
+}
+
+// checkRowAnchors warns about rows whose anchors collide with each other or
+// with a section's, e.g. "a.b" and "a-b", since a link would only reach the
+// first of them.
+func checkRowAnchors(sections []*Section, cfg *Config) {
+	if !cfg.Readme.RowAnchors {
+		return
+	}
+	owner := map[string]string{}
+	for _, sec := range sections {
+		owner[slugify(sec.Name)] = fmt.Sprintf("section %q", sec.Name)
+		if cfg.Readme.AnchorPrefix != "" {
+			owner[cfg.Readme.AnchorPrefix+slugify(sec.Name)] = fmt.Sprintf("section %q", sec.Name)
+		}
+	}
+	for _, sec := range sections {
+		for _, p := range sec.Parameters {
+			if p.Skip() {
+				continue
+			}
+			slug := rowAnchor(p.DocName(), cfg)
+			// A key documented twice has one anchor twice, which is harmless.
+			if prev, ok := owner[slug]; ok && prev != fmt.Sprintf("parameter %q", p.DocName()) {
+				console.WarnAt(p.File, p.Line, "parameter %q shares the anchor #%s with %s", p.DocName(), slug, prev)
+				continue
+			}
+			owner[slug] = fmt.Sprintf("parameter %q", p.DocName())
+		}
+	}
+}
+
+// checkModifierNames reports modifiers that are not configured, such as
+// "[arrray]", which would otherwise be ignored without a trace.
+func checkModifierNames(params []*Parameter, cfg *Config) error {
+	if cfg.Validation.AllowUnknownModifiers {
+		return nil
+	}
+	known := configuredModifiers(cfg)
+	var unknown bool
+	for _, p := range params {
+		for _, m := range p.Modifiers {
+			if name, _, _ := strings.Cut(m, ":"); !known[name] {
+				console.ErrorAt(p.File, p.Line, "%s: unknown modifier %q", p.Name, m)
+				unknown = true
+			}
+		}
+	}
+	if unknown {
+		return errors.New("unknown modifiers found")
+	}
+	return nil
+}
+
+// checkSectionPrefixes reports parameters of a section listed in
+// validation.sectionPrefixes whose key is not below one of its prefixes.
+func checkSectionPrefixes(sections []*Section, cfg *Config) error {
+	if len(cfg.Validation.SectionPrefixes) == 0 {
+		return nil
+	}
+	declared := map[string]bool{}
+	var outside bool
+	for _, sec := range sections {
+		declared[sec.Name] = true
+		prefixes, ok := cfg.Validation.SectionPrefixes[sec.Name]
+		if !ok {
+			continue
+		}
+		for _, p := range sec.Parameters {
+			if p.Skip() || slices.ContainsFunc(prefixes, func(prefix string) bool {
+				return isKeyBelow(p.Name, strings.TrimSuffix(prefix, "."))
+			}) {
+				continue
+			}
+			console.ErrorAt(p.File, p.Line, "%s is documented in section %q but is not under %s",
+				p.Name, sec.Name, strings.Join(prefixes, ", "))
+			outside = true
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Validation.SectionPrefixes)) {
+		if !declared[name] {
+			console.Warn("validation.sectionPrefixes names unknown section %q", name)
+		}
+	}
+	if outside {
+		return errors.New("parameters outside their section's key prefix found")
+	}
+	return nil
+}
+
+// isKeyBelow reports whether key is prefix itself or a key nested in it.
+func isKeyBelow(key, prefix string) bool {
+	rest, ok := strings.CutPrefix(key, prefix)
+	return ok && (rest == "" || strings.HasPrefix(rest, ".") || strings.HasPrefix(rest, "["))
+}
+
+// checkCommentFormat reports comments carrying a metadata tag behind another
+// prefix than cfg.Comments.Format, e.g. "# @param" when "##" is expected.
+// The parser ignores them, so the parameter silently goes undocumented.
+func checkCommentFormat(files []valuesFile, cfg *Config) error {
+	if !cfg.Validation.CommentFormat {
+		return nil
+	}
+	tags := map[string]bool{}
+	for _, tag := range []string{cfg.Tags.Param, cfg.Tags.Section, cfg.Tags.DescriptionStart,
+		cfg.Tags.DescriptionEnd, cfg.Tags.Skip, cfg.Tags.Extra, cfg.Tags.Default,
+		cfg.Tags.ExampleStart, cfg.Tags.ExampleEnd} {
+		tags[tag] = true
+	}
+	re := regexp.MustCompile(`^\s*(#+)\s*(@\S+)`)
+	var found bool
+	for _, f := range files {
+		for i, line := range strings.Split(string(f.data), "\n") {
+			m := re.FindStringSubmatch(line)
+			if m == nil || !tags[m[2]] || m[1] == cfg.Comments.Format {
+				continue
+			}
+			console.ErrorAt(f.path, i+1, "%s uses comment prefix %q instead of %q", m[2], m[1], cfg.Comments.Format)
+			found = true
+		}
+	}
+	if found {
+		return errors.New("inconsistent comment prefixes found")
+	}
+	return nil
+}
+
+// slugify turns a heading into its GitHub anchor: lower case, punctuation
+// dropped, spaces replaced by hyphens.
+func slugify(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(heading)) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// checkExclusiveGroups warns when values.yaml itself sets more than one member
+// of a "oneOf-group". A member is set when it, or any key below it, holds a
+// value other than null, false or an empty string/collection.
+func checkExclusiveGroups(values []*Parameter, meta []*Parameter, cfg *Config) {
+	groups := exclusiveGroups(meta, cfg)
+	for _, g := range sortedKeys(groups) {
+		var set []string
+		for _, member := range groups[g] {
+			for _, v := range values {
+				if (v.Name == member.Name || strings.HasPrefix(v.Name, member.Name+".") ||
+					strings.HasPrefix(v.Name, member.Name+"[")) && isSetValue(v.Value) {
+					set = append(set, member.Name)
+					break
+				}
+			}
+		}
+		if len(set) > 1 {
+			console.Warn("oneOf-group %s: more than one member is set: %s", g, strings.Join(set, ", "))
+		}
+	}
+}
+
+func isSetValue(v interface{}) bool {
+	switch vv := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return vv
+	case string:
+		return vv != ""
+	case []interface{}:
+		return len(vv) > 0
+	case map[string]interface{}:
+		return len(vv) > 0
+	}
+	return true
+}
+
+//-------------------------------------------------------------------------
+// Schema input – the reverse workflow, parameters read from a JSON schema
+//-------------------------------------------------------------------------
+
+// getSchemaMetadata builds the metadata from a hand-written schema: every
+// leaf property becomes a parameter in a single section named after the
+// schema title. When valuesPath is set the schema keys are checked against
+// values.yaml like comment metadata would be.
+func getSchemaMetadata(schemaPath string, files []valuesFile, cfg *Config) (*Metadata, error) {
+	raw, err := ioutil.ReadFile(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil, fmt.Errorf("%s: %w", schemaPath, err)
+	}
+	inlineLocalRefs(root)
+
+	title, _ := root["title"].(string)
+	if title == "" {
+		title = "Parameters"
+	}
+	sec := &Section{Name: title}
+	meta := &Metadata{}
+	meta.AddSection(sec)
+	for _, p := range schemaParameters("", root) {
+		p.Section = sec.Name
+		sec.Parameters = append(sec.Parameters, p)
+		meta.AddParameter(p)
+	}
+
+	if len(files) == 0 {
+		return meta, nil
+	}
+	valuesObj, _, err := createValuesObject(files, cfg)
+	if err != nil {
+		return nil, err
+	}
+	valuesObj = excludeParameters(valuesObj, cfg)
+	meta.dropParameters(excludedParameters(meta.Parameters, cfg))
+	return meta, checkKeys(valuesObj, meta.Parameters).report(cfg)
+}
+
+// inlineLocalRefs replaces every "$ref" pointing into the same document,
+// such as "#/definitions/limits", with a copy of its target, so that readers
+// which do not follow references see the whole schema. References to other
+// documents, unresolvable ones and cycles are left in place. Targets are
+// copied from the schema as it was before any inlining, so the result does
+// not depend on the order in which maps are visited.
+func inlineLocalRefs(root map[string]interface{}) {
+	orig, _ := deepCopyValue(root).(map[string]interface{})
+	var resolve func(v interface{}, seen []string) interface{}
+	resolve = func(v interface{}, seen []string) interface{} {
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := vv["$ref"].(string); ok && strings.HasPrefix(ref, "#/") && !slices.Contains(seen, ref) {
+				if target, ok := lookupPointer(orig, ref); ok {
+					return resolve(deepCopyValue(target), append(seen, ref))
+				}
+			}
+			for k, c := range vv {
+				vv[k] = resolve(c, seen)
+			}
+		case []interface{}:
+			for i, c := range vv {
+				vv[i] = resolve(c, seen)
+			}
+		}
+		return v
+	}
+	resolve(root, nil)
+}
+
+// lookupPointer returns the value at a "#/a/b" JSON pointer within root.
+func lookupPointer(root map[string]interface{}, ref string) (interface{}, bool) {
+	var cur interface{} = root
+	for _, tok := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		tok = strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[tok]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// schemaParameters flattens the properties of node into parameters, sorted
+// by key. Objects with properties are descended into; anything else is a leaf.
+func schemaParameters(prefix string, node map[string]interface{}) []*Parameter {
+	props, _ := node["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var out []*Parameter
+	for _, k := range keys {
+		child, ok := props[k].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := k
+		if prefix != "" {
+			name = prefix + "." + k
+		}
+		if _, nested := child["properties"].(map[string]interface{}); nested {
+			out = append(out, schemaParameters(name, child)...)
+			continue
+		}
+		p := NewParameter(name)
+		p.Description, _ = child["description"].(string)
+		p.Value = child["default"]
+		p.Type, _ = child["type"].(string)
+		out = append(out, p)
+	}
+	return out
+}
+
+//-------------------------------------------------------------------------
+// Values linting (--lint-values) – a small JSON schema validator covering
+// the keywords this tool emits plus common constraints
+//-------------------------------------------------------------------------
+
+// lintValues validates the merged values files against the schema at
+// schemaPath and reports every violation with the key path it was found at.
+func lintValues(files []valuesFile, schemaPath string, cfg *Config) error {
+	raw, err := ioutil.ReadFile(schemaPath)
+	if err != nil {
+		return err
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return fmt.Errorf("%s: %w", schemaPath, err)
+	}
+	inlineLocalRefs(schema)
+	values, _, err := mergedValues(files, cfg)
+	if err != nil {
+		return err
+	}
+	violations := validateValue("", values, schema)
+	for _, v := range violations {
+		console.Error("%s", v)
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("%d value(s) do not match %s", len(violations), schemaPath)
+	}
+	return nil
+}
+
+// validateValue returns the violations of v against schema s, each prefixed
+// with the key path. Unknown keywords, including $ref to other documents,
+// are ignored.
+func validateValue(path string, v interface{}, s map[string]interface{}) []string {
+	at := path
+	if at == "" {
+		at = "(root)"
+	}
+	var out []string
+	fail := func(format string, args ...interface{}) {
+		out = append(out, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t, ok := s["type"]; ok && !typeAllowed(t, v, s["nullable"] == true) {
+		fail("expected %s, got %s", describeType(t), jsonType(v))
+		// Further keywords would only repeat the mismatch.
+		return out
+	}
+	if enum, ok := s["enum"].([]interface{}); ok && !containsValue(enum, v) {
+		b, _ := json.Marshal(enum)
+		fail("value must be one of %s", b)
+	}
+	if c, ok := s["const"]; ok && !equalValues(c, v) {
+		b, _ := json.Marshal(c)
+		fail("value must be %s", b)
+	}
+
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		props, _ := s["properties"].(map[string]interface{})
+		if req, ok := s["required"].([]interface{}); ok {
+			for _, r := range req {
+				if name, _ := r.(string); name != "" {
+					if _, set := vv[name]; !set {
+						fail("missing required key %q", name)
+					}
+				}
+			}
+		}
+		if pn, ok := s["propertyNames"].(map[string]interface{}); ok {
+			if pat, ok := pn["pattern"].(string); ok {
+				if re, err := regexp.Compile(pat); err == nil {
+					for _, k := range sortedMapKeys(vv) {
+						if !re.MatchString(k) {
+							fail("key %q does not match %s", k, pat)
+						}
+					}
+				}
+			}
+		}
+		for _, k := range sortedMapKeys(vv) {
+			child := joinPath(path, k)
+			if ps, ok := props[k].(map[string]interface{}); ok {
+				out = append(out, validateValue(child, vv[k], ps)...)
+				continue
+			}
+			switch ap := s["additionalProperties"].(type) {
+			case bool:
+				if !ap {
+					out = append(out, child+": key is not allowed by the schema")
+				}
+			case map[string]interface{}:
+				out = append(out, validateValue(child, vv[k], ap)...)
+			}
+		}
+	case []interface{}:
+		if n, ok := schemaNumber(s, "minItems"); ok && float64(len(vv)) < n {
+			fail("expected at least %v items, got %d", n, len(vv))
+		}
+		if n, ok := schemaNumber(s, "maxItems"); ok && float64(len(vv)) > n {
+			fail("expected at most %v items, got %d", n, len(vv))
+		}
+		switch items := s["items"].(type) {
+		case map[string]interface{}:
+			for i, e := range vv {
+				out = append(out, validateValue(fmt.Sprintf("%s[%d]", path, i), e, items)...)
+			}
+		case []interface{}:
+			// A tuple: each position has its own schema, and elements
+			// past the listed positions are not constrained.
+			for i, e := range vv {
+				if i >= len(items) {
+					break
+				}
+				if is, ok := items[i].(map[string]interface{}); ok {
+					out = append(out, validateValue(fmt.Sprintf("%s[%d]", path, i), e, is)...)
+				}
+			}
+		}
+	case string:
+		n := float64(utf8.RuneCountInString(vv))
+		if min, ok := schemaNumber(s, "minLength"); ok && n < min {
+			fail("expected at least %v characters", min)
+		}
+		if max, ok := schemaNumber(s, "maxLength"); ok && n > max {
+			fail("expected at most %v characters", max)
+		}
+		if pat, ok := s["pattern"].(string); ok {
+			if re, err := regexp.Compile(pat); err == nil && !re.MatchString(vv) {
+				fail("%q does not match %s", vv, pat)
+			}
+		}
+	default:
+		if f, ok := toFloat(v); ok {
+			if min, ok := schemaNumber(s, "minimum"); ok && f < min {
+				fail("%v is less than the minimum %v", f, min)
+			}
+			if max, ok := schemaNumber(s, "maximum"); ok && f > max {
+				fail("%v is greater than the maximum %v", f, max)
+			}
+		}
+	}
+
+	// Combinators: only report that the value as a whole does not match, the
+	// branches' own messages would be confusing.
+	matches := func(sub interface{}) bool {
+		m, ok := sub.(map[string]interface{})
+		return !ok || len(validateValue(path, v, m)) == 0
+	}
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if m, ok := sub.(map[string]interface{}); ok {
+				out = append(out, validateValue(path, v, m)...)
+			}
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		n := 0
+		for _, sub := range anyOf {
+			if matches(sub) {
+				n++
+			}
+		}
+		if n == 0 {
+			fail("value matches none of the anyOf alternatives")
+		}
+	}
+	if oneOf, ok := s["oneOf"].([]interface{}); ok {
+		n := 0
+		for _, sub := range oneOf {
+			if matches(sub) {
+				n++
+			}
+		}
+		if n != 1 {
+			fail("value must match exactly one oneOf alternative, matches %d", n)
+		}
+	}
+	if not, ok := s["not"]; ok && matches(not) {
+		fail("value matches a schema it must not match")
+	}
+	if cond, ok := s["if"]; ok {
+		branch := s["else"]
+		if matches(cond) {
+			branch = s["then"]
+		}
+		if m, ok := branch.(map[string]interface{}); ok {
+			out = append(out, validateValue(path, v, m)...)
+		}
+	}
+	return out
+}
+
+// typeAllowed reports whether v satisfies a "type" keyword, which is either a
+// name or a list of names. Names this validator does not know accept anything.
+func typeAllowed(t interface{}, v interface{}, nullable bool) bool {
+	if v == nil && nullable {
+		return true
+	}
+	var names []string
+	switch tt := t.(type) {
+	case string:
+		names = []string{tt}
+	case []interface{}:
+		for _, n := range tt {
+			if s, ok := n.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+	for _, n := range names {
+		if !schemaTypes[n] || n == jsonType(v) || (n == "number" && jsonType(v) == "integer") {
+			return true
+		}
+	}
+	return len(names) == 0
+}
+
+func describeType(t interface{}) string {
+	if list, ok := t.([]interface{}); ok {
+		var names []string
+		for _, n := range list {
+			names = append(names, fmt.Sprint(n))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// jsonType names the JSON schema type of a decoded YAML value; whole numbers
+// are integers.
+func jsonType(v interface{}) string {
+	if f, ok := toFloat(v); ok {
+		if f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	}
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func schemaNumber(s map[string]interface{}, key string) (float64, bool) {
+	return toFloat(s[key])
+}
+
+// equalValues compares a schema value (decoded from JSON) with a YAML value
+// by their JSON encoding, so 1 and 1.0 are equal.
+func equalValues(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
+
+func containsValue(list []interface{}, v interface{}) bool {
+	for _, e := range list {
+		if equalValues(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+//-------------------------------------------------------------------------
+// runReadmeGenerator – public entry similar to JS runReadmeGenerator
+//-------------------------------------------------------------------------
+
+// RunCLI parses the command line and runs the generator, as the
+// readme-generator-for-helm command does.
+func RunCLI() error {
+	opts, err := parseFlags()
+	if err != nil {
+		return err
+	}
+	console.w = os.Stdout
+	if multiChart(opts) && !opts.version {
+		return runCharts(opts)
+	}
+	return runReadmeGenerator(opts)
+}
+
+// chartSummary is what the index of a multi-chart run lists about a chart.
+type chartSummary struct {
+	name    string // "name" of Chart.yaml, or the directory name
+	readme  string // README.md of the chart; empty when it has none
+	heading string // text of the README's parameters heading
+	params  int    // documented parameters
+}
+
+// runCharts runs the command on every --chart-dir, taking its paths from the
+// chart, and then writes the --index. Every chart is run even when an earlier
+// one fails; the index is only written when all of them succeed.
+func runCharts(opts *options) error {
+	var errs []error
+	var charts []*chartSummary
+	for _, dir := range opts.chartDirs {
+		chart := *opts
+		chart.chartDirs, chart.indexPath = nil, ""
+		chart.chartDir = dir
+		chart.summary = &chartSummary{name: chartName(dir)}
+		console.Info("chart %s", dir)
+		// --strict applies to the warnings of each chart.
+		console.warnings = 0
+		err := applyChartDir(&chart)
+		if err == nil {
+			err = checkOptions(&chart)
+		}
+		if err == nil {
+			err = runReadmeGenerator(&chart)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", dir, err))
+		}
+		// A dry run that finds drift still has the chart to index.
+		if err == nil || errors.Is(err, ErrDrift) {
+			charts = append(charts, chart.summary)
+		}
+	}
+	if opts.indexPath != "" && len(charts) == len(opts.chartDirs) {
+		errs = append(errs, writeIndex(opts, charts))
+	}
+	return errors.Join(errs...)
+}
+
+// chartName returns the name from dir/Chart.yaml, or the directory name when
+// the file does not set one.
+func chartName(dir string) string {
+	var chart struct {
+		Name string `yaml:"name"`
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "Chart.yaml")); err == nil {
+		_ = yaml.Unmarshal(data, &chart)
+	}
+	if chart.Name == "" {
+		return filepath.Base(filepath.Clean(dir))
+	}
+	return chart.Name
+}
+
+// writeIndex writes opts.indexPath, or compares it with a dry run.
+func writeIndex(opts *options, charts []*chartSummary) error {
+	data := []byte(renderIndex(charts, filepath.Dir(opts.indexPath)))
+	if opts.dryRun {
+		return checkDrift([]pendingFile{{opts.indexPath, data}}, opts.postFormat)
+	}
+	if err := writeFile(opts.indexPath, data, os.FileMode(opts.fileMode)); err != nil {
+		return err
+	}
+	if err := runPostFormat(opts.postFormat, opts.indexPath); err != nil {
+		return err
+	}
+	fmt.Fprintln(console.w, "Index updated ✅")
+	return nil
+}
+
+// renderIndex lists the charts in a table linking the parameters section of
+// every README, relative to dir, with the number of documented parameters.
+func renderIndex(charts []*chartSummary, dir string) string {
+	rows := [][]string{{"Chart", "Parameters"}}
+	for _, c := range charts {
+		name := c.name
+		if c.readme != "" {
+			link := c.readme
+			if rel, err := filepath.Rel(dir, c.readme); err == nil {
+				link = rel
+			}
+			name = fmt.Sprintf("[%s](%s)", c.name, filepath.ToSlash(link))
+			if c.heading != "" {
+				name = fmt.Sprintf("[%s](%s#%s)", c.name, filepath.ToSlash(link), slugify(c.heading))
+			}
+		}
+		rows = append(rows, []string{name, strconv.Itoa(c.params)})
+	}
+	w := make([]int, 2)
+	for _, r := range rows {
+		for j, c := range r {
+			w[j] = max(w[j], utf8.RuneCountInString(c))
+		}
+	}
+	var b strings.Builder
+	b.WriteString("# Charts\n\n")
+	writeTableRow(&b, rows[0], w)
+	writeTableSeparator(&b, w)
+	for _, r := range rows[1:] {
+		writeTableRow(&b, r, w)
+	}
+	return b.String()
+}
+
+// paramsHeading returns the text of the README heading that the parameters
+// table is placed under, or "" when the README has none.
+func paramsHeading(readme []byte, cfg *Config) string {
+	re := regexp.MustCompile(fmt.Sprintf(`^[ \t>]*?##+ (%s.*)$`, cfg.Regexp.ParamsSectionTitle))
+	for _, l := range strings.Split(string(readme), "\n") {
+		if m := re.FindStringSubmatch(strings.TrimRight(l, "\r")); m != nil {
+			return strings.TrimSpace(m[1])
+		}
+	}
+	return ""
+}
+
+func runReadmeGenerator(opts *options) error {
+	if opts.version {
+		fmt.Println("Version:", Version)
+		return nil
+	}
+
+	console.debug = opts.debug
+	// Keep stdout for the README alone.
+	if opts.outputPath == "-" {
+		console.w = os.Stderr
+	}
+
+	cfg, err := LoadConfig(opts.configPaths)
+	if err != nil {
+		return err
+	}
+
+	files, err := readValuesFiles(opts.valuesPaths)
+	if err != nil {
+		return err
+	}
+	var comments []valuesFile
+	if len(opts.metadataPaths) > 0 {
+		if opts.fromSchema != "" {
+			return errors.New("--metadata cannot be combined with --from-schema")
+		}
+		if comments, err = readValuesFiles(opts.metadataPaths); err != nil {
+			return err
+		}
+	}
+
+	// Linting alone does not need metadata comments.
+	lintOnly := opts.readmePath == "" && opts.schemaPath == "" && opts.paramsJSONPath == "" && !opts.modifierReport && !opts.outline
+	if opts.lintValues != "" && lintOnly {
+		if err := lintValues(files, opts.lintValues, cfg); err != nil {
+			return err
+		}
+		fmt.Println("Values match the schema ✅")
+		return nil
+	}
+	if opts.summarizeComplexValues {
+		cfg.Readme.SummarizeComplexValues = true
+	}
+	if opts.sectionSummary {
+		cfg.Readme.SectionSummary = true
+	}
+	if opts.rowAnchors {
+		cfg.Readme.RowAnchors = true
+	}
+	if opts.preserveLiterals {
+		cfg.Readme.PreserveLiterals = true
+	}
+	if opts.paramSort != "" {
+		if opts.paramSort != paramSortFile && opts.paramSort != paramSortAlpha {
+			return fmt.Errorf("invalid --param-sort %q (expected %s or %s)", opts.paramSort, paramSortFile, paramSortAlpha)
+		}
+		cfg.Readme.ParamSort = opts.paramSort
+	}
+	if opts.schemaID != "" {
+		cfg.Schema.ID = opts.schemaID
+	}
+	if opts.schemaRootType != "" {
+		if !schemaTypes[opts.schemaRootType] {
+			return fmt.Errorf("invalid --schema-root-type %q", opts.schemaRootType)
+		}
+		cfg.Schema.RootType = opts.schemaRootType
+	}
+	if opts.schemaSections {
+		cfg.Schema.Sections = true
+	}
+	if opts.checkExtraShadowing {
+		cfg.Validation.ExtraShadowing = true
+	}
+	if opts.checkSectionAnchors {
+		cfg.Validation.SectionAnchors = true
+	}
+	if opts.checkCommentFormat {
+		cfg.Validation.CommentFormat = true
+	}
+	if opts.allowUnknownModifiers {
+		cfg.Validation.AllowUnknownModifiers = true
+	}
+	if opts.excludePath != "" {
+		patterns, err := readExcludeFile(opts.excludePath)
+		if err != nil {
+			return err
+		}
+		cfg.Exclude = append(cfg.Exclude, patterns...)
+	}
+	if opts.noFail {
+		if opts.strict {
+			return errors.New("--no-fail cannot be combined with --strict or --fail-on-warning")
+		}
+		cfg.Validation.NoFail = true
+	}
+	if opts.compact {
+		cfg.Readme.Compact = true
+	}
+	if opts.rowsPerTable > 0 {
+		cfg.Readme.RowsPerTable = opts.rowsPerTable
+	}
+	if opts.maxTableWidth > 0 {
+		cfg.Readme.MaxTableWidth = opts.maxTableWidth
+	}
+
+	// Without --keep-going the first error stops the run; with it every
+	// stage still runs and all errors are reported together. Nothing is
+	// written once an error has been seen.
+	var errs []error
+	seen := map[string]bool{}
+	proceed := func(err error) bool {
+		if err == nil {
+			return true
+		}
+		// README and schema apply modifiers to the same parameters; report
+		// each problem once.
+		for _, line := range strings.Split(err.Error(), "\n") {
+			if !seen[line] {
+				seen[line] = true
+				errs = append(errs, errors.New(line))
+			}
+		}
+		return opts.keepGoing
+	}
+
+	var meta *Metadata
+	if opts.fromSchema != "" {
+		meta, err = getSchemaMetadata(opts.fromSchema, files, cfg)
+	} else {
+		meta, err = getParsedMetadata(files, comments, cfg)
+	}
+	if meta == nil {
+		return err
+	}
+	if opts.modifierReport {
+		printModifierReport(os.Stdout, meta.Parameters, cfg)
+	}
+	if !proceed(err) {
+		return errors.Join(errs...)
+	}
+
+	if opts.outline {
+		params, err := buildParamsToRender(cloneParameters(meta.Parameters), cfg)
+		if !proceed(err) {
+			return errors.Join(errs...)
+		}
+		printOutline(os.Stdout, params)
+	}
+
+	if opts.lintValues != "" {
+		if !proceed(lintValues(files, opts.lintValues, cfg)) {
+			return errors.Join(errs...)
+		}
+	}
+
+	if opts.readmePath != "" {
+		for _, sec := range meta.Sections {
+			sec.Parameters, err = buildParamsToRender(sec.Parameters, cfg)
+			if !proceed(err) {
+				return errors.Join(errs...)
+			}
+			sortSectionParameters(sec.Parameters, cfg)
+		}
+	}
+
+	var schema SchemaObject
+	if opts.schemaPath != "" {
+		meta.Parameters, err = buildParamsToRender(meta.Parameters, cfg)
+		if !proceed(err) {
+			return errors.Join(errs...)
+		}
+		schema, err = buildOpenAPISchema(meta.Parameters, meta.RootType, cfg)
+		if !proceed(err) {
+			return errors.Join(errs...)
+		}
+		if len(opts.subchartSchemas) > 0 && schema != nil {
+			if !proceed(combineSubchartSchemas(schema, opts.subchartSchemas)) {
+				return errors.Join(errs...)
+			}
+		}
+	}
+
+	var dump []*Parameter
+	if opts.paramsJSONPath != "" {
+		dump, err = buildParamsToRender(meta.Parameters, cfg)
+		if !proceed(err) {
+			return errors.Join(errs...)
+		}
+	}
+
+	var readme []byte
+	var cache *renderCache
+	if opts.readmePath != "" && len(errs) == 0 {
+		if opts.cachePath != "" {
+			cache = loadRenderCache(opts.cachePath, cfg)
+		}
+		raw, err := ioutil.ReadFile(opts.readmePath)
+		if err != nil {
+			return err
+		}
+		if readme, err = insertReadmeTable(raw, meta.Sections, cfg, cache); err != nil {
+			return err
+		}
+	}
+
+	if opts.summary != nil {
+		for _, p := range meta.Parameters {
+			if !p.Skip() {
+				opts.summary.params++
+			}
+		}
+		if opts.readmePath != "" {
+			opts.summary.readme = opts.readmePath
+			opts.summary.heading = paramsHeading(readme, cfg)
+		}
+	}
+
+	if opts.strict && console.warnings > 0 {
+		proceed(fmt.Errorf("%d warning(s) treated as errors (--strict)", console.warnings))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// The README template is only rewritten in place without --output.
+	readmeOut := opts.readmePath
+	if opts.outputPath != "" {
+		readmeOut = opts.outputPath
+	}
+
+	if opts.dryRun {
+		var files []pendingFile
+		if opts.readmePath != "" {
+			files = append(files, pendingFile{readmeOut, readme})
+		}
+		if opts.schemaPath != "" {
+			files = append(files, pendingFile{opts.schemaPath, schemaJSON(schema)})
+		}
+		if opts.paramsJSONPath != "" {
+			data, _ := json.MarshalIndent(dump, "", "    ")
+			files = append(files, pendingFile{opts.paramsJSONPath, data})
+		}
+		return checkDrift(files, opts.postFormat)
+	}
+
+	if opts.readmePath != "" {
+		if readmeOut == "-" {
+			if _, err := os.Stdout.Write(readme); err != nil {
+				return err
+			}
+		} else if err := writeFile(readmeOut, readme, os.FileMode(opts.fileMode)); err != nil {
+			return err
+		}
+		if cache != nil {
+			if err := cache.save(opts.cachePath, os.FileMode(opts.fileMode)); err != nil {
+				return err
+			}
+		}
+		if readmeOut != "-" {
+			if err := runPostFormat(opts.postFormat, readmeOut); err != nil {
+				return err
+			}
+			fmt.Fprintln(console.w, "README updated ✅")
+		}
+	}
+
+	if opts.schemaPath != "" {
+		if err := writeOpenAPISchema(opts.schemaPath, schema, os.FileMode(opts.fileMode)); err != nil {
+			return err
+		}
+		if err := runPostFormat(opts.postFormat, opts.schemaPath); err != nil {
+			return err
+		}
+		fmt.Fprintln(console.w, "Schema generated ✅")
+	}
+
+	if opts.paramsJSONPath != "" {
+		data, _ := json.MarshalIndent(dump, "", "    ")
+		if err := writeFile(opts.paramsJSONPath, data, os.FileMode(opts.fileMode)); err != nil {
+			return err
+		}
+		if err := runPostFormat(opts.postFormat, opts.paramsJSONPath); err != nil {
+			return err
+		}
+		fmt.Fprintln(console.w, "Parameters exported ✅")
+	}
+
+	return nil
+}
+
+//-------------------------------------------------------------------------
+// Modifier usage report (--modifier-report)
+//-------------------------------------------------------------------------
+
+// printModifierReport lists to w how many parameters use each modifier, most
+// used first. "name:value" modifiers are counted by name; names that are not
+// configured modifiers are flagged, as they are usually typos.
+func printModifierReport(w io.Writer, params []*Parameter, cfg *Config) {
+	known := configuredModifiers(cfg)
+
+	counts := map[string]int{}
+	documented, withModifiers := 0, 0
+	for _, p := range params {
+		if p.Skip() {
+			continue
+		}
+		documented++
+		if len(p.Modifiers) > 0 {
+			withModifiers++
+		}
+		seen := map[string]bool{}
+		for _, m := range p.Modifiers {
+			name, _, _ := strings.Cut(m, ":")
+			if !seen[name] {
+				seen[name] = true
+				counts[name]++
+			}
+		}
+	}
+
+	names := make([]string, 0, len(counts))
+	width := 0
+	for n := range counts {
+		names = append(names, n)
+		if len(n) > width {
+			width = len(n)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	fmt.Fprintf(w, "Modifier usage (%d of %d parameters use modifiers):\n", withModifiers, documented)
+	for _, n := range names {
+		note := ""
+		if !known[n] {
+			note = "  (not a configured modifier)"
+		}
+		fmt.Fprintf(w, "  %-*s %4d%s\n", width, n, counts[n], note)
+	}
+}
+
+//-------------------------------------------------------------------------
+// Parameter outline (--outline)
+//-------------------------------------------------------------------------
+
+// printOutline writes the parameters to w as a tree rebuilt from their key
+// paths, two spaces per level. Siblings keep the order in which they were
+// first documented, so keys of one map stay together even when their
+// metadata is interleaved with other keys. Each documented key shows its
+// effective type; intermediate keys are printed once, without one.
+func printOutline(w io.Writer, params []*Parameter) {
+	type outlineNode struct {
+		seg      string
+		typ      string
+		children []*outlineNode
+	}
+	root := &outlineNode{}
+	nodes := map[string]*outlineNode{}
+	for _, p := range params {
+		segs := pathSegments(p.Name)
+		parent, path := root, ""
+		for i, seg := range segs {
+			if path != "" && !strings.HasPrefix(seg, "[") {
+				path += "."
+			}
+			path += seg
+			n, ok := nodes[path]
+			if !ok {
+				n = &outlineNode{seg: seg}
+				nodes[path] = n
+				parent.children = append(parent.children, n)
+			}
+			if i == len(segs)-1 && p.Type != "" {
+				n.typ = p.Type
+			}
+			parent = n
+		}
+	}
+	var write func(n *outlineNode, depth int)
+	write = func(n *outlineNode, depth int) {
+		for _, c := range n.children {
+			line := strings.Repeat("  ", depth) + c.seg
+			if c.typ != "" {
+				line += " (" + c.typ + ")"
+			}
+			fmt.Fprintln(w, line)
+			write(c, depth+1)
+		}
+	}
+	write(root, 0)
+}
+
+//-------------------------------------------------------------------------
+// Dry run (--dry-run)
+//-------------------------------------------------------------------------
+
+// ErrDrift is returned by a dry run when a file is not up to date; the
+// command exits with status 2 for it.
+var ErrDrift = errors.New("generated files are out of date")
+
+// writeFile writes data to path. A zero mode keeps the usual behaviour: new
+// files get 0644 less the umask and existing files keep their permissions.
+// Any other mode is applied as is, regardless of both.
+func writeFile(path string, data []byte, mode os.FileMode) error {
+	if mode == 0 {
+		return ioutil.WriteFile(path, data, 0644)
+	}
+	if err := ioutil.WriteFile(path, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+type pendingFile struct {
+	path string
+	data []byte
+}
+
+// checkDrift compares what would be written with the files on disk, printing
+// a unified diff for each one that differs. Output is passed through the
+// post-format command first (on a temporary copy) so that formatting alone
+// does not count as drift.
+func checkDrift(files []pendingFile, postFormat string) error {
+	stale := 0
+	for _, f := range files {
+		want, err := formatted(f, postFormat)
+		if err != nil {
+			return err
+		}
+		have, err := ioutil.ReadFile(f.path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if bytes.Equal(have, want) {
+			continue
+		}
+		stale++
+		fmt.Print(unifiedDiff(f.path, string(have), string(want)))
+	}
+	if stale > 0 {
+		return fmt.Errorf("%w: %d file(s) would change", ErrDrift, stale)
+	}
+	fmt.Println("Files are up to date ✅")
+	return nil
+}
+
+// formatted returns f's content as the post-format command would leave it.
+func formatted(f pendingFile, postFormat string) ([]byte, error) {
+	if strings.TrimSpace(postFormat) == "" {
+		return f.data, nil
+	}
+	// Same directory and extension, so the formatter picks the same config.
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".dry-run-*"+filepath.Ext(f.path))
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(f.data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := runPostFormat(postFormat, tmp.Name()); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(tmp.Name())
+}
+
+// unifiedDiff renders the change from a to b as a single-hunk unified diff:
+// the common leading and trailing lines are trimmed and three lines of
+// context kept around the rest.
+func unifiedDiff(path, a, b string) string {
+	const context = 3
+	lines := func(s string) []string {
+		if s == "" {
+			return nil // a missing file has no lines at all
+		}
+		l := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+		if !strings.HasSuffix(s, "\n") {
+			// The marker is part of the last line, so that adding the
+			// final newline changes that line.
+			l[len(l)-1] += "\n\\ No newline at end of file"
+		}
+		return l
+	}
+	al, bl := lines(a), lines(b)
+	pre := 0
+	for pre < len(al) && pre < len(bl) && al[pre] == bl[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(al)-pre && suf < len(bl)-pre && al[len(al)-1-suf] == bl[len(bl)-1-suf] {
+		suf++
+	}
+	from := pre - context
+	if from < 0 {
+		from = 0
+	}
+	tail := suf
+	if tail > context {
+		tail = context
+	}
+	aEnd, bEnd := len(al)-suf+tail, len(bl)-suf+tail
+
+	var d strings.Builder
+	fmt.Fprintf(&d, "--- %s\n+++ %s (generated)\n", path, path)
+	fmt.Fprintf(&d, "@@ -%s +%s @@\n", hunkRange(from, aEnd-from), hunkRange(from, bEnd-from))
+	for _, l := range al[from:pre] {
+		d.WriteString(" " + l + "\n")
+	}
+	for _, l := range al[pre : len(al)-suf] {
+		d.WriteString("-" + l + "\n")
+	}
+	for _, l := range bl[pre : len(bl)-suf] {
+		d.WriteString("+" + l + "\n")
+	}
+	for _, l := range al[len(al)-suf : aEnd] {
+		d.WriteString(" " + l + "\n")
+	}
+	return d.String()
+}
+
+// hunkRange formats a 0-based start and a length as a unified diff range.
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+// runPostFormat runs the user's formatter on a written file. The command is
+// split on whitespace (no shell is involved) and the path is appended.
+func runPostFormat(command, path string) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil
+	}
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("post-format %q on %s: %w", command, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,1160 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// readmeHeading is the README that tests render the parameters table into.
+const readmeHeading = "## Parameters\n"
+
+// mustGenerate runs Generate on values, below a section so that their
+// parameters are rendered, with both outputs requested and fails the test on
+// error.
+func mustGenerate(t *testing.T, values string, cfg *Config) Result {
+	t.Helper()
+	values = "## @section Values\n" + values
+	res, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Schema: true, Config: cfg})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	return res
+}
+
+// property decodes the schema and returns the node of a flattened key such
+// as "a.b[0].c"; indexes select the items schema.
+func property(t *testing.T, schema []byte, path string) map[string]interface{} {
+	t.Helper()
+	var node map[string]interface{}
+	if err := json.Unmarshal(schema, &node); err != nil {
+		t.Fatalf("decoding schema: %v", err)
+	}
+	for _, seg := range pathSegments(path) {
+		var child map[string]interface{}
+		var ok bool
+		if strings.HasPrefix(seg, "[") {
+			child, ok = node["items"].(map[string]interface{})
+		} else {
+			props, _ := node["properties"].(map[string]interface{})
+			child, ok = props[seg].(map[string]interface{})
+		}
+		if !ok {
+			t.Fatalf("schema has no property %q:\n%s", path, schema)
+		}
+		node = child
+	}
+	return node
+}
+
+// containsRow reports whether the README has a table row for name.
+func containsRow(readme, name string) bool {
+	for _, line := range strings.Split(readme, "\n") {
+		if strings.HasPrefix(line, "| `"+name+"` ") {
+			return true
+		}
+	}
+	return false
+}
+
+// tableRow returns the README table row of the parameter name.
+func tableRow(t *testing.T, readme, name string) string {
+	t.Helper()
+	for _, line := range strings.Split(readme, "\n") {
+		if strings.HasPrefix(line, "| `"+name+"` ") {
+			return line
+		}
+	}
+	t.Fatalf("README has no row for %s:\n%s", name, readme)
+	return ""
+}
+
+func TestRequiredKeepsWrittenValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    string
+		key       string
+		cell      string
+		dflt      interface{}
+		noDefault bool
+	}{
+		{
+			name:   "string",
+			values: "## @param c [string,required] C\nc: hello\n",
+			key:    "c",
+			cell:   "`hello`",
+			dflt:   "hello",
+		},
+		{
+			name:   "populated array",
+			values: "## @param a [array,required] A\na: [x, y]\n",
+			key:    "a",
+			cell:   "`[\"x\",\"y\"]`",
+			dflt:   []interface{}{"x", "y"},
+		},
+		{
+			name:   "populated object",
+			values: "## @param o [object,required] O\no:\n  k: v\n",
+			key:    "o",
+			cell:   "`{\"k\":\"v\"}`",
+			dflt:   map[string]interface{}{"k": "v"},
+		},
+		{
+			name:      "null",
+			values:    "## @param n [string,required] N\nn:\n",
+			key:       "n",
+			cell:      "`<must be set>`",
+			noDefault: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := mustGenerate(t, tt.values, nil)
+			if row := tableRow(t, res.Readme, tt.key); !strings.Contains(row, tt.cell) {
+				t.Errorf("row %q does not show %s", row, tt.cell)
+			}
+			prop := property(t, res.Schema, tt.key)
+			got, ok := prop["default"]
+			if tt.noDefault {
+				if ok {
+					t.Errorf("default = %v, want none", got)
+				}
+				return
+			}
+			if !ok || !jsonEqual(got, tt.dflt) {
+				t.Errorf("default = %v, want %v", got, tt.dflt)
+			}
+		})
+	}
+}
+
+// tableCells splits a README table row into its trimmed cells; escaped
+// pipes stay inside their cell.
+func tableCells(row string) []string {
+	var cells []string
+	start := 1
+	for i := 1; i < len(row); i++ {
+		if row[i] == '|' && row[i-1] != '\\' {
+			cells = append(cells, strings.TrimSpace(row[start:i]))
+			start = i + 1
+		}
+	}
+	return cells
+}
+
+// jsonEqual compares two decoded JSON values.
+func jsonEqual(a, b interface{}) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
+
+func TestBooleanCoercion(t *testing.T) {
+	tests := []struct {
+		name     string
+		coercion string
+		values   string
+		key      string
+		typ      string
+		dflt     interface{}
+		cell     string
+		warnings int
+	}{
+		{
+			name:     "coerce string",
+			coercion: booleanCoercionCoerce,
+			values:   "## @param legacy [boolean] L\nlegacy: \"true\"\n",
+			key:      "legacy",
+			typ:      "boolean",
+			dflt:     true,
+			cell:     "`true` (written as `\"true\"`)",
+		},
+		{
+			name:     "coerce number",
+			coercion: booleanCoercionCoerce,
+			values:   "## @param legacy [boolean] L\nlegacy: 0\n",
+			key:      "legacy",
+			typ:      "boolean",
+			dflt:     false,
+			cell:     "`false` (written as `0`)",
+		},
+		{
+			name:     "none keeps the string",
+			coercion: booleanCoercionNone,
+			values:   "## @param legacy [boolean] L\nlegacy: \"true\"\n",
+			key:      "legacy",
+			typ:      "string",
+			dflt:     "true",
+			cell:     "`true`",
+			warnings: 1,
+		},
+		{
+			name:     "real boolean",
+			coercion: booleanCoercionNone,
+			values:   "## @param enabled [boolean] E\nenabled: true\n",
+			key:      "enabled",
+			typ:      "boolean",
+			dflt:     true,
+			cell:     "`true`",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.BooleanCoercion = tt.coercion
+			res := mustGenerate(t, tt.values, cfg)
+			prop := property(t, res.Schema, tt.key)
+			if prop["type"] != tt.typ || !jsonEqual(prop["default"], tt.dflt) {
+				t.Errorf("type %v, default %v; want %s, %v", prop["type"], prop["default"], tt.typ, tt.dflt)
+			}
+			if row := tableRow(t, res.Readme, tt.key); !strings.Contains(row, tt.cell) {
+				t.Errorf("row %q does not show %s", row, tt.cell)
+			}
+			if res.Warnings != tt.warnings {
+				t.Errorf("Warnings = %d, want %d", res.Warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestPasswordFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		format interface{}
+	}{
+		{name: "string", values: "## @param pw [password] P\npw: secret\n", format: "password"},
+		{name: "unset", values: "## @param pw [password] P\npw:\n", format: "password"},
+		{name: "integer", values: "## @param pw [password] P\npw: 1234\n", format: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prop := property(t, mustGenerate(t, tt.values, nil).Schema, "pw")
+			if prop["format"] != tt.format || prop["writeOnly"] != true {
+				t.Errorf("format %v, writeOnly %v; want %v, true", prop["format"], prop["writeOnly"], tt.format)
+			}
+		})
+	}
+}
+
+func TestTypeConflict(t *testing.T) {
+	const values = "## @param port [string] Port\nport: 8080\n"
+	tests := []struct {
+		policy string
+		typ    string
+		dflt   interface{}
+		err    string
+	}{
+		{policy: typeConflictModifierWins, typ: "string", dflt: ""},
+		{policy: typeConflictValueWins, typ: "integer", dflt: 8080},
+		{policy: typeConflictError, err: `type conflict for port: value is integer but modifier "string" implies string`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.TypeConflict = tt.policy
+			if tt.err != "" {
+				_, err := Generate(Options{Values: []byte(values), Schema: true, Config: cfg})
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			prop := property(t, mustGenerate(t, values, cfg).Schema, "port")
+			if prop["type"] != tt.typ || !jsonEqual(prop["default"], tt.dflt) {
+				t.Errorf("type %v, default %v; want %s, %v", prop["type"], prop["default"], tt.typ, tt.dflt)
+			}
+		})
+	}
+}
+
+func TestTypeConflictUnsetValue(t *testing.T) {
+	for _, policy := range []string{typeConflictModifierWins, typeConflictValueWins, typeConflictError} {
+		t.Run(policy, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.TypeConflict = policy
+			prop := property(t, mustGenerate(t, "## @param list [array] List\nlist:\n", cfg).Schema, "list")
+			if prop["type"] != "array" {
+				t.Errorf("type = %v, want array", prop["type"])
+			}
+		})
+	}
+}
+
+func TestPlainAsDescription(t *testing.T) {
+	const values = `## Number of replicas
+## to run
+replicaCount: 1
+## Ignored, documented below
+## @param image Image to run
+image: nginx
+`
+	tests := []struct {
+		plain bool
+		rows  map[string]string
+		err   string
+	}{
+		{plain: false, err: "Missing metadata for key: replicaCount"},
+		{plain: true, rows: map[string]string{"replicaCount": "Number of replicas to run", "image": "Image to run"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.plain), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Comments.PlainAsDescription = tt.plain
+			if tt.err != "" {
+				var log strings.Builder
+				_, err := Generate(Options{Values: []byte("## @section S\n" + values), Schema: true, Config: cfg, Log: &log})
+				if err == nil || !strings.Contains(log.String(), tt.err) {
+					t.Fatalf("error = %v, log %q; want %q", err, log.String(), tt.err)
+				}
+				return
+			}
+			readme := mustGenerate(t, values, cfg).Readme
+			for key, desc := range tt.rows {
+				if got := tableCells(tableRow(t, readme, key))[1]; got != desc {
+					t.Errorf("%s: description %q, want %q", key, got, desc)
+				}
+			}
+		})
+	}
+}
+
+func TestYAMLTagTyping(t *testing.T) {
+	tests := []struct {
+		name   string
+		scalar string
+		typ    string
+		dflt   interface{}
+	}{
+		{name: "quoted number", scalar: `"3.10"`, typ: "string", dflt: "3.10"},
+		{name: "plain number", scalar: "3.10", typ: "number", dflt: 3.1},
+		{name: "plain yes", scalar: "yes", typ: "string", dflt: "yes"},
+		{name: "plain on", scalar: "on", typ: "string", dflt: "on"},
+		{name: "tagged yes", scalar: "!!bool yes", typ: "boolean", dflt: true},
+		{name: "tagged string", scalar: "!!str 42", typ: "string", dflt: "42"},
+		{name: "timestamp", scalar: "2024-01-02", typ: "string", dflt: "2024-01-02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := mustGenerate(t, "## @param v V\nv: "+tt.scalar+"\n", nil)
+			prop := property(t, res.Schema, "v")
+			if prop["type"] != tt.typ || !jsonEqual(prop["default"], tt.dflt) {
+				t.Errorf("type %v, default %v; want %s, %v", prop["type"], prop["default"], tt.typ, tt.dflt)
+			}
+		})
+	}
+}
+
+func TestDefaultRef(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		cell   string
+		desc   string
+		log    string
+	}{
+		{
+			name:   "documented key",
+			values: "## @param nameOverride Name\nnameOverride: app\n## @param fullnameOverride [default-ref:nameOverride] Full name\nfullnameOverride: \"\"\n",
+			cell:   "defaults to the value of `nameOverride`",
+			desc:   "Full name. Defaults to the value of nameOverride.",
+		},
+		{
+			name:   "no description",
+			values: "## @param nameOverride Name\nnameOverride: app\n## @param fullnameOverride [default-ref:nameOverride]\nfullnameOverride: \"\"\n",
+			cell:   "defaults to the value of `nameOverride`",
+			desc:   "Defaults to the value of nameOverride.",
+		},
+		{
+			name:   "dangling",
+			values: "## @param fullnameOverride [default-ref:nameOverride] Full name\nfullnameOverride: \"\"\n",
+			log:    "fullnameOverride defaults to non existing key: nameOverride",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.log != "" {
+				var log strings.Builder
+				_, err := Generate(Options{Values: []byte("## @section S\n" + tt.values), Schema: true, Log: &log})
+				if err == nil || !strings.Contains(log.String(), tt.log) {
+					t.Fatalf("error = %v, log %q; want %q", err, log.String(), tt.log)
+				}
+				return
+			}
+			res := mustGenerate(t, tt.values, nil)
+			if got := tableCells(tableRow(t, res.Readme, "fullnameOverride"))[2]; got != tt.cell {
+				t.Errorf("value cell %q, want %q", got, tt.cell)
+			}
+			if got := property(t, res.Schema, "fullnameOverride")["description"]; got != tt.desc {
+				t.Errorf("description %q, want %q", got, tt.desc)
+			}
+		})
+	}
+}
+
+func TestFromFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"files/config.yaml": "listen: 8080\nlog: debug\n",
+		"files/long.txt":    strings.Repeat("x", 100) + "\n",
+	})
+	tests := []struct {
+		name   string
+		values string
+		max    int
+		cell   string
+		dflt   string
+		err    string
+	}{
+		{
+			name:   "small file",
+			values: "## @param config [fromFile:files/config.yaml] Config\nconfig: \"\"\n",
+			max:    80,
+			cell:   "`listen: 8080\\nlog: debug`",
+			dflt:   "listen: 8080\nlog: debug\n",
+		},
+		{
+			name:   "truncated",
+			values: "## @param long [fromFile:files/long.txt] Long\nlong: \"\"\n",
+			max:    10,
+			cell:   "`xxxxxxxxxx…`",
+			dflt:   strings.Repeat("x", 100) + "\n",
+		},
+		{
+			name:   "missing file",
+			values: "## @param config [fromFile:files/missing.yaml] Config\nconfig: \"\"\n",
+			err:    "config: open ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.FileDefaultMaxLength = tt.max
+			values := []byte("## @section S\n" + tt.values)
+			res, err := Generate(Options{Values: values, Readme: []byte(readmeHeading), Schema: true, Config: cfg, Dir: dir})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			key := strings.Fields(tt.values)[2]
+			if got := tableCells(tableRow(t, res.Readme, key))[2]; got != tt.cell {
+				t.Errorf("value cell %q, want %q", got, tt.cell)
+			}
+			if got := property(t, res.Schema, key)["default"]; got != tt.dflt {
+				t.Errorf("default %q, want %q", got, tt.dflt)
+			}
+		})
+	}
+}
+
+func TestExtraShadowing(t *testing.T) {
+	const shadowing = "## @extra image.tag Tag\n## @param image.repository Repository\nimage:\n  repository: nginx\n  tag: \"1.0\"\n"
+	tests := []struct {
+		name     string
+		values   string
+		check    bool
+		reported bool
+	}{
+		{name: "leaf key", values: shadowing, check: true, reported: true},
+		{
+			name:   "intermediate object",
+			values: "## @extra image Image settings\n## @param image.tag Tag\nimage:\n  tag: \"1.0\"\n",
+			check:  true,
+		},
+		{name: "missing key", values: "## @extra ingress.tls TLS\n## @param a A\na: 1\n", check: true},
+		{name: "check disabled", values: shadowing},
+	}
+	const report = "@extra parameter shadows existing key: image.tag"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Validation.ExtraShadowing = tt.check
+			var log strings.Builder
+			_, err := Generate(Options{Values: []byte("## @section S\n" + tt.values), Schema: true, Config: cfg, Log: &log})
+			if got := strings.Contains(log.String(), report); got != tt.reported {
+				t.Errorf("reported = %v, want %v; log %q", got, tt.reported, log.String())
+			}
+			if tt.reported && err == nil {
+				t.Error("Generate succeeded, want an error")
+			}
+			if tt.check && !tt.reported && err != nil {
+				t.Errorf("Generate: %v; log %q", err, log.String())
+			}
+		})
+	}
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		log    string
+	}{
+		{name: "unique", values: "## @param a A\na: 1\n## @param b.c C\nb:\n  c: 2\n"},
+		{
+			name:   "top level",
+			values: "## @param a A\na: 1\nb: 2\na: 3\n",
+			log:    `values.yaml:5: duplicate key "a" (first defined at line 3)`,
+		},
+		{
+			name:   "nested",
+			values: "## @param b.c C\nb:\n  c: 1\n  c: 2\n",
+			log:    `values.yaml:5: duplicate key "c" (first defined at line 4)`,
+		},
+		{
+			name:   "same name at different levels",
+			values: "## @param a.a A\na:\n  a: 1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log strings.Builder
+			_, err := Generate(Options{Values: []byte("## @section S\n" + tt.values), Schema: true, Log: &log})
+			if tt.log == "" {
+				if err != nil {
+					t.Fatalf("Generate: %v; log %q", err, log.String())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(log.String(), tt.log) {
+				t.Fatalf("error = %v, log %q; want %q", err, log.String(), tt.log)
+			}
+		})
+	}
+}
+
+func TestPercentage(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		typ      string
+		cell     string
+		warnings int
+	}{
+		{name: "integer", value: "80", typ: "integer", cell: "`80%`"},
+		{name: "whole float", value: "80.0", typ: "integer", cell: "`80%`"},
+		{name: "fraction", value: "80.5", typ: "number", cell: "`80.5%`", warnings: 1},
+		{name: "string", value: `"80"`, typ: "string", cell: "`80`", warnings: 1},
+		{name: "unset", value: "", typ: "integer", cell: "`null`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := mustGenerate(t, "## @param hpa.target [percentage] Target\nhpa:\n  target: "+tt.value+"\n", nil)
+			prop := property(t, res.Schema, "hpa.target")
+			if prop["type"] != tt.typ || prop["minimum"] != 0.0 || prop["maximum"] != 100.0 {
+				t.Errorf("type %v, minimum %v, maximum %v; want %s, 0, 100", prop["type"], prop["minimum"], prop["maximum"], tt.typ)
+			}
+			if got := tableCells(tableRow(t, res.Readme, "hpa.target"))[2]; got != tt.cell {
+				t.Errorf("value cell %q, want %q", got, tt.cell)
+			}
+			if res.Warnings != tt.warnings {
+				t.Errorf("Warnings = %d, want %d", res.Warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestSectionAnchors(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []string
+		check    bool
+		log      string
+	}{
+		{name: "distinct", sections: []string{"Ingress", "Ingress TLS"}, check: true},
+		{name: "space and dash", sections: []string{"Ingress TLS", "Ingress-TLS"}, check: true,
+			log: `sections "Ingress TLS" and "Ingress-TLS" share the anchor #ingress-tls`},
+		{name: "case", sections: []string{"Metrics", "metrics"}, check: true,
+			log: `sections "Metrics" and "metrics" share the anchor #metrics`},
+		{name: "check disabled", sections: []string{"Ingress TLS", "Ingress-TLS"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var values strings.Builder
+			for i, sec := range tt.sections {
+				fmt.Fprintf(&values, "## @section %s\n## @param p%d P\np%d: 1\n", sec, i, i)
+			}
+			cfg := DefaultConfig()
+			cfg.Validation.SectionAnchors = tt.check
+			var log strings.Builder
+			_, err := Generate(Options{Values: []byte(values.String()), Schema: true, Config: cfg, Log: &log})
+			if tt.log == "" {
+				if err != nil {
+					t.Fatalf("Generate: %v; log %q", err, log.String())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(log.String(), tt.log) {
+				t.Fatalf("error = %v, log %q; want %q", err, log.String(), tt.log)
+			}
+		})
+	}
+}
+
+func TestMergeKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		cells  map[string]string
+		log    string
+	}{
+		{
+			name:   "merged defaults",
+			values: "## @param defaults.cpu C\n## @param worker.cpu W\n## @param worker.mem M\ndefaults: &d\n  cpu: 1\nworker:\n  <<: *d\n  mem: 2\n",
+			cells:  map[string]string{"worker.cpu": "`1`", "worker.mem": "`2`"},
+		},
+		{
+			name:   "explicit key overrides",
+			values: "## @param defaults.cpu C\n## @param worker.cpu W\ndefaults: &d\n  cpu: 1\nworker:\n  <<: *d\n  cpu: 4\n",
+			cells:  map[string]string{"defaults.cpu": "`1`", "worker.cpu": "`4`"},
+		},
+		{
+			name:   "list of maps",
+			values: "## @param a.x X\n## @param b.y Y\n## @param c.x X\n## @param c.y Y\na: &a\n  x: 1\nb: &b\n  y: 2\nc:\n  <<: [*a, *b]\n",
+			cells:  map[string]string{"c.x": "`1`", "c.y": "`2`"},
+		},
+		{
+			name:   "merged key needs metadata",
+			values: "## @param defaults.cpu C\n## @param worker.mem M\ndefaults: &d\n  cpu: 1\nworker:\n  <<: *d\n  mem: 2\n",
+			log:    "Missing metadata for key: worker.cpu",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.log != "" {
+				var log strings.Builder
+				_, err := Generate(Options{Values: []byte("## @section S\n" + tt.values), Schema: true, Log: &log})
+				if err == nil || !strings.Contains(log.String(), tt.log) {
+					t.Fatalf("error = %v, log %q; want %q", err, log.String(), tt.log)
+				}
+				return
+			}
+			res := mustGenerate(t, tt.values, nil)
+			for key, want := range tt.cells {
+				if got := tableCells(tableRow(t, res.Readme, key))[2]; got != want {
+					t.Errorf("%s: value cell %q, want %q", key, got, want)
+				}
+			}
+			if strings.Contains(res.Readme, "<<") || strings.Contains(string(res.Schema), "<<") {
+				t.Errorf("a << key leaked:\n%s\n%s", res.Readme, res.Schema)
+			}
+		})
+	}
+}
+
+func TestEmptyModifierValue(t *testing.T) {
+	tests := []struct {
+		modifier string
+		err      string
+	}{
+		{modifier: "default:", err: `a: modifier "default" needs a value (default:<value>)`},
+		{modifier: "default: ", err: `a: modifier "default" needs a value (default:<value>)`},
+		{modifier: "pattern:", err: `a: modifier "pattern" needs a value (pattern:<value>)`},
+		{modifier: "default-ref:", err: `a: modifier "default-ref" needs a value (default-ref:<value>)`},
+		{modifier: "fromFile:", err: `a: modifier "fromFile" needs a value (fromFile:<value>)`},
+		{modifier: "deprecated:removed-in=", err: `a: invalid deprecated modifier "removed-in=" (expected deprecated:removed-in=<version>)`},
+		{modifier: "propertyNames:*a", err: "a: invalid propertyNames pattern: error parsing regexp: missing argument to repetition operator: `*`"},
+		{modifier: "min:", err: "unknown modifiers found"},
+		{modifier: "default:0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.modifier, func(t *testing.T) {
+			_, err := Generate(Options{Values: []byte("## @section S\n## @param a [" + tt.modifier + "] A\na: 1\n"), Schema: true})
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("Generate: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	tests := []struct {
+		name      string
+		modifier  string
+		desc      string
+		removedIn interface{}
+	}{
+		{name: "removal version", modifier: "deprecated:removed-in=2.0.0", desc: "Deprecated; will be removed in 2.0.0. Old setting", removedIn: "2.0.0"},
+		{name: "plain", modifier: "deprecated", desc: "Deprecated. Old setting"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := mustGenerate(t, "## @param legacy ["+tt.modifier+"] Old setting\nlegacy: 1\n", nil)
+			if got := tableCells(tableRow(t, res.Readme, "legacy"))[1]; got != tt.desc {
+				t.Errorf("description %q, want %q", got, tt.desc)
+			}
+			prop := property(t, res.Schema, "legacy")
+			if prop["deprecated"] != true || prop["x-removed-in"] != tt.removedIn || prop["description"] != "Old setting" {
+				t.Errorf("deprecated %v, x-removed-in %v, description %q; want true, %v, %q",
+					prop["deprecated"], prop["x-removed-in"], prop["description"], tt.removedIn, "Old setting")
+			}
+		})
+	}
+}
+
+func TestStability(t *testing.T) {
+	tests := []struct {
+		name     string
+		modifier string
+		levels   []string
+		desc     string
+		err      string
+	}{
+		{name: "beta", modifier: "stability:beta", desc: "New setting (beta)"},
+		{name: "with another modifier", modifier: "string,stability:alpha", desc: "New setting (alpha)"},
+		{name: "custom level", modifier: "stability:experimental", levels: []string{"experimental"}, desc: "New setting (experimental)"},
+		{name: "unknown level", modifier: "stability:bta", err: `feature: unknown stability "bta" (expected one of stable, beta, alpha)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			if tt.levels != nil {
+				cfg.StabilityLevels = tt.levels
+			}
+			values := "## @section Values\n## @param feature [" + tt.modifier + "] New setting\nfeature: x\n"
+			res, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Schema: true, Config: cfg})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if got := tableCells(tableRow(t, res.Readme, "feature"))[1]; got != tt.desc {
+				t.Errorf("description %q, want %q", got, tt.desc)
+			}
+			level := tt.modifier[strings.Index(tt.modifier, ":")+1:]
+			if prop := property(t, res.Schema, "feature"); prop["x-stability"] != level || prop["description"] != "New setting" {
+				t.Errorf("x-stability %v, description %q; want %s, %q", prop["x-stability"], prop["description"], level, "New setting")
+			}
+		})
+	}
+}
+
+func TestCollapseAliases(t *testing.T) {
+	const anchors = "## @param limits.cpu CPU\n## @param limits.mem Memory\nlimits: &limits\n  cpu: 1\n  mem: 2Gi\n" +
+		"## @param hosts[0] Host\nhosts: &hosts [a]\n"
+	tests := []struct {
+		name     string
+		values   string
+		collapse bool
+		row      string
+		log      string
+		err      string
+	}{
+		{
+			name:     "map alias",
+			values:   "## @param worker.resources Worker resources\nworker:\n  resources: *limits\n",
+			collapse: true,
+			row:      "| `worker.resources` | Worker resources | `{\"cpu\":1,\"mem\":\"2Gi\"}` |",
+			log:      "worker.resources is an alias of &limits and is documented as a single key",
+		},
+		{
+			name:     "list alias",
+			values:   "## @param mirrors Mirrors\nmirrors: *hosts\n",
+			collapse: true,
+			row:      "| `mirrors`    | Mirrors     | `[\"a\"]` |",
+			log:      "mirrors is an alias of &hosts and is documented as a single key",
+		},
+		{
+			name:   "disabled",
+			values: "## @param worker.resources Worker resources\nworker:\n  resources: *limits\n",
+			err:    "Missing metadata for key: worker.resources.cpu",
+		},
+		{
+			name:     "merge key",
+			values:   "## @param worker.cpu Worker CPU\n## @param worker.mem Worker memory\nworker:\n  <<: *limits\n",
+			collapse: true,
+			row:      "| `worker.cpu` | Worker CPU    | `1`   |",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Validation.CollapseAliases = tt.collapse
+			var log strings.Builder
+			res, err := Generate(Options{Values: []byte("## @section Values\n" + anchors + tt.values), Readme: []byte(readmeHeading),
+				Config: cfg, Log: &log})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(log.String(), tt.err) {
+					t.Fatalf("error %v, log %q; want %q", err, log.String(), tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v; log %q", err, log.String())
+			}
+			if !strings.Contains(res.Readme, tt.row+"\n") {
+				t.Errorf("README has no row %q:\n%s", tt.row, res.Readme)
+			}
+			if !strings.Contains(log.String(), tt.log) {
+				t.Errorf("log %q does not contain %q", log.String(), tt.log)
+			}
+		})
+	}
+}
+
+func TestSourcePositions(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		log    string
+	}{
+		{name: "missing metadata", values: "## @param a A\na: 1\nimage:\n  tag: x\n",
+			log: "values.yaml:4: ERROR: Missing metadata for key: image.tag\n"},
+		{name: "metadata without key", values: "## @param a A\na: 1\n## @param image.digest Digest\n",
+			log: "values.yaml:3: WARNING: Metadata provided for non existing key: image.digest"},
+		{name: "key reached through an alias", values: "base: &b\n  x: 1\n## @param base.x X\nother: *b\n",
+			log: "\nERROR: Missing metadata for key: other.x\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log strings.Builder
+			Generate(Options{Values: []byte(tt.values), Readme: []byte(readmeHeading), Log: &log})
+			if got := "\n" + log.String(); !strings.Contains(got, tt.log) {
+				t.Errorf("log %q does not contain %q", log.String(), tt.log)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(path, []byte("## @section S\n\n## @param a A\na: 1\n## @param b B\nb: 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := ParseMetadata(path, DefaultConfig())
+	if err != nil {
+		t.Fatalf("ParseMetadata: %v", err)
+	}
+	for i, want := range []int{3, 5} {
+		if p := meta.Parameters[i]; p.File != path || p.Line != want {
+			t.Errorf("%s at %s:%d, want %s:%d", p.Name, p.File, p.Line, path, want)
+		}
+	}
+}
+
+func TestDefaultTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		key    string
+		shown  string
+		dflt   interface{}
+		err    string
+	}{
+		{name: "empty string", values: "## @param clusterDomain Domain\n## @default cluster.local\nclusterDomain: \"\"\n",
+			key: "clusterDomain", shown: "`cluster.local`", dflt: "cluster.local"},
+		{name: "free text", values: "## @param replicas Replicas\n## @default one per zone\nreplicas: 0\n",
+			key: "replicas", shown: "`one per zone`", dflt: "one per zone"},
+		{name: "without param", values: "## @default cluster.local\n## @param a A\na: 1\n",
+			err: "values.yaml:2: @default without a preceding @param"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err != "" {
+				var log strings.Builder
+				_, err := Generate(Options{Values: []byte("## @section Values\n" + tt.values), Readme: []byte(readmeHeading), Log: &log})
+				if err == nil || !strings.Contains(err.Error()+log.String(), tt.err) {
+					t.Fatalf("error %v, log %q; want %q", err, log.String(), tt.err)
+				}
+				return
+			}
+			res := mustGenerate(t, tt.values, nil)
+			if got := tableCells(tableRow(t, res.Readme, tt.key))[2]; got != tt.shown {
+				t.Errorf("value %s, want %s", got, tt.shown)
+			}
+			if dflt := property(t, res.Schema, tt.key)["default"]; dflt != tt.dflt {
+				t.Errorf("schema default %v, want %v", dflt, tt.dflt)
+			}
+		})
+	}
+}
+
+func TestCommentFormatCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		values  string
+		disable bool
+		report  string
+	}{
+		{name: "single hash", values: "# @param b B\nb: 2\n", report: `values.yaml:4: ERROR: @param uses comment prefix "#" instead of "##"`},
+		{name: "triple hash", values: "### @param b B\nb: 2\n", report: `values.yaml:4: ERROR: @param uses comment prefix "###" instead of "##"`},
+		{name: "section tag", values: "# @section Other\n", report: `values.yaml:4: ERROR: @section uses comment prefix "#" instead of "##"`},
+		{name: "custom format", format: "#", values: "## @param b B\nb: 2\n", report: `values.yaml:4: ERROR: @param uses comment prefix "##" instead of "#"`},
+		{name: "disabled", values: "# @param b B\nb: 2\n", disable: true},
+		{name: "tag inside a comment", values: "# see @param docs\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Validation.CommentFormat = !tt.disable
+			format := "##"
+			if tt.format != "" {
+				format = tt.format
+				cfg.Comments.Format = tt.format
+			}
+			values := format + " @section S\n" + format + " @param a A\na: 1\n" + tt.values
+			var log strings.Builder
+			_, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Config: cfg, Log: &log})
+			prefixErr := err != nil && strings.Contains(err.Error(), "inconsistent comment prefixes found")
+			if prefixErr != (tt.report != "") {
+				t.Errorf("error %v; want a prefix error: %t", err, tt.report != "")
+			}
+			if tt.report != "" && !strings.Contains(log.String(), tt.report) {
+				t.Errorf("log %q does not contain %q", log.String(), tt.report)
+			}
+		})
+	}
+}
+
+func TestAllowedVersions(t *testing.T) {
+	tests := []struct {
+		constraint string
+		desc       string
+	}{
+		{constraint: ">=1.2.0", desc: "Tag. Supported versions: `>=1.2.0`."},
+		{constraint: ">=1.2.0 <2.0.0", desc: "Tag. Supported versions: `>=1.2.0 <2.0.0`."},
+		{constraint: "~1.4", desc: "Tag. Supported versions: `~1.4`."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			res := mustGenerate(t, "## @param image.tag [allowedVersions:"+tt.constraint+"] Tag\nimage:\n  tag: \"1.3\"\n", nil)
+			if got := tableCells(tableRow(t, res.Readme, "image.tag"))[1]; got != tt.desc {
+				t.Errorf("description %q, want %q", got, tt.desc)
+			}
+			prop := property(t, res.Schema, "image.tag")
+			if prop["x-allowed-versions"] != tt.constraint || prop["description"] != "Tag" || prop["default"] != "1.3" {
+				t.Errorf("x-allowed-versions %v, description %v, default %v; want %s, Tag, 1.3",
+					prop["x-allowed-versions"], prop["description"], prop["default"], tt.constraint)
+			}
+		})
+	}
+}
+
+func TestSectionPrefixes(t *testing.T) {
+	const values = "## @section Ingress\n## @param ingress.enabled Enabled\n## @param ingressClass Class\n## @param hosts[0] Host\n" +
+		"ingress:\n  enabled: true\ningressClass: x\nhosts: [a]\n"
+	tests := []struct {
+		name     string
+		prefixes map[string][]string
+		errors   []string
+		warning  string
+	}{
+		{name: "prefix is a whole key", prefixes: map[string][]string{"Ingress": {"ingress"}}, errors: []string{
+			`values.yaml:3: ERROR: ingressClass is documented in section "Ingress" but is not under ingress`,
+			`values.yaml:4: ERROR: hosts[0] is documented in section "Ingress" but is not under ingress`,
+		}},
+		{name: "several prefixes", prefixes: map[string][]string{"Ingress": {"ingress", "ingressClass", "hosts"}}},
+		{name: "unlisted section", prefixes: map[string][]string{"Other": {"other"}},
+			warning: `validation.sectionPrefixes names unknown section "Other"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Validation.SectionPrefixes = tt.prefixes
+			var log strings.Builder
+			res, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Config: cfg, Log: &log})
+			if (err != nil) != (tt.errors != nil) {
+				t.Fatalf("error %v; log %q", err, log.String())
+			}
+			for _, e := range tt.errors {
+				if !strings.Contains(log.String(), e+"\n") {
+					t.Errorf("log %q does not contain %q", log.String(), e)
+				}
+			}
+			if tt.warning != "" && (res.Warnings != 1 || !strings.Contains(log.String(), tt.warning)) {
+				t.Errorf("Warnings = %d, log %q; want %q", res.Warnings, log.String(), tt.warning)
+			}
+		})
+	}
+}
+
+func TestUnknownModifiers(t *testing.T) {
+	tests := []struct {
+		name      string
+		modifiers string
+		allow     bool
+		rename    bool
+		errors    []string
+	}{
+		{name: "typo", modifiers: "arrray", errors: []string{`values.yaml:2: ERROR: a: unknown modifier "arrray"`}},
+		{name: "with a value", modifiers: "nulable, x-custom:1", errors: []string{
+			`values.yaml:2: ERROR: a: unknown modifier "nulable"`,
+			`values.yaml:2: ERROR: a: unknown modifier "x-custom:1"`,
+		}},
+		{name: "known with a value", modifiers: "default:x"},
+		{name: "allowed", modifiers: "arrray, x-custom:1", allow: true},
+		{name: "renamed modifier", modifiers: "list", rename: true},
+		{name: "default name of a renamed modifier", modifiers: "array", rename: true,
+			errors: []string{`values.yaml:2: ERROR: a: unknown modifier "array"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Validation.AllowUnknownModifiers = tt.allow
+			if tt.rename {
+				cfg.Modifiers.Array = "list"
+			}
+			var log strings.Builder
+			values := "## @section S\n## @param a [" + tt.modifiers + "] A\na: []\n"
+			_, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Config: cfg, Log: &log})
+			if (err != nil) != (tt.errors != nil) {
+				t.Fatalf("error %v; log %q", err, log.String())
+			}
+			for _, e := range tt.errors {
+				if !strings.Contains(log.String(), e+"\n") {
+					t.Errorf("log %q does not contain %q", log.String(), e)
+				}
+			}
+		})
+	}
+}
+
+func TestMultilineDescriptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		plain  bool
+		on     string
+		off    string
+	}{
+		{name: "continued", values: "## @param a Annotations for the Ingress,\n## e.g. to select\n## a controller.\na: {}\n",
+			on: "Annotations for the Ingress, e.g. to select a controller.", off: "Annotations for the Ingress,"},
+		{name: "empty comment line", values: "## @param a First\n##\n## ref: https://example.com\na: 1\n", on: "First", off: "First"},
+		{name: "next tag", values: "## @param a First\n## more\n## @param b B\na: 1\nb: 2\n", on: "First more", off: "First"},
+		{name: "blank line", values: "## @param a First\n## more\n\n## trailing\na: 1\n", on: "First more", off: "First"},
+	}
+	for _, tt := range tests {
+		for _, on := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/%t", tt.name, on), func(t *testing.T) {
+				cfg := DefaultConfig()
+				cfg.Comments.MultilineDescriptions = on
+				res := mustGenerate(t, tt.values, cfg)
+				want := tt.off
+				if on {
+					want = tt.on
+				}
+				if got := tableCells(tableRow(t, res.Readme, "a"))[1]; got != want {
+					t.Errorf("description %q, want %q", got, want)
+				}
+				if got := property(t, res.Schema, "a")["description"]; got != want {
+					t.Errorf("schema description %q, want %q", got, want)
+				}
+			})
+		}
+	}
+
+	t.Run("not a plain comment", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Comments.MultilineDescriptions = true
+		cfg.Comments.PlainAsDescription = true
+		res := mustGenerate(t, "## @param a First\n## more\na: 1\n## Second\nb: 2\n", cfg)
+		for key, want := range map[string]string{"a": "First more", "b": "Second"} {
+			if got := tableCells(tableRow(t, res.Readme, key))[1]; got != want {
+				t.Errorf("%s: description %q, want %q", key, got, want)
+			}
+		}
+	})
+}
+
+func TestMatchKeyPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{"global.**", "global", true},
+		{"global.**", "global.image.registry", true},
+		{"global.**", "globalImage", false},
+		{"global.**", "sub.global", false},
+		{"*.internal", "db.internal", true},
+		{"*.internal", "db.ext.internal", false},
+		{"*.internal", "internal", false},
+		{"a.**.c", "a.c", true},
+		{"a.**.c", "a.b.d.c", true},
+		{"a.**.c", "a.b.d", false},
+		{"**.password", "db.auth.password", true},
+		{"image.t?g", "image.tag", true},
+		{"image", "image.tag", false},
+		{"items[0].name", "items[0].name", true},
+		{"items[*].name", "items[3].name", true},
+		{"items[*]", "items[0]", true},
+		{"items[*]", "name[0]", false},
+		{"items[*]", "items", false},
+		{"items.*", "items[0]", false},
+		{"items.**", "items[0].name", true},
+		{"**.name", "items[0].name", true},
+		{"*[*].name", "items[0].name", true},
+		{"m[*][1]", "m[0][1]", true},
+		{"items.[ab]", "items.a", true},
+	}
+	for _, tt := range tests {
+		if got := matchKeyPattern(tt.pattern, tt.key); got != tt.want {
+			t.Errorf("matchKeyPattern(%q, %q) = %t, want %t", tt.pattern, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestExclude(t *testing.T) {
+	const values = "## @section S\n## @param image.tag Tag\n## @param global.registry Registry\nimage:\n  tag: v1\n" +
+		"global:\n  registry: docker.io\n  pullSecrets: []\ndb:\n  internal: true\n"
+	const arrays = "## @section S\n## @param image.tag Tag\nimage:\n  tag: v1\nsidecars:\n  - name: log\n"
+	tests := []struct {
+		name    string
+		values  string
+		exclude []string
+		rows    []string
+		err     string
+	}{
+		{name: "none", err: "metadata errors found"},
+		{name: "undocumented and documented keys", exclude: []string{"global.**", "*.internal"}, rows: []string{"image.tag"}},
+		{name: "partial", exclude: []string{"global.pullSecrets", "db.**"}, rows: []string{"image.tag", "global.registry"}},
+		{name: "invalid pattern", exclude: []string{"global.[a"}, err: `invalid exclude pattern "global.[a"`},
+		{name: "array entries", values: arrays, exclude: []string{"sidecars[*].name"}, rows: []string{"image.tag"}},
+		{name: "other array", values: arrays, exclude: []string{"hosts[*]"}, err: "metadata errors found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Exclude = tt.exclude
+			if tt.values == "" {
+				tt.values = values
+			}
+			var log strings.Builder
+			res, err := Generate(Options{Values: []byte(tt.values), Readme: []byte(readmeHeading), Schema: true, Config: cfg, Log: &log})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v\n%s", err, log.String())
+			}
+			for _, key := range []string{"image.tag", "global.registry", "global.pullSecrets", "db.internal"} {
+				if want := slices.Contains(tt.rows, key); containsRow(res.Readme, key) != want {
+					t.Errorf("row %s present = %t, want %t:\n%s", key, !want, want, res.Readme)
+				}
+			}
+			if strings.Contains(string(res.Schema), `"db"`) {
+				t.Errorf("schema documents an excluded key:\n%s", res.Schema)
+			}
+		})
+	}
+}
@@ -0,0 +1,1378 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chartValues returns a values.yaml with the given number of sections of
+// params parameters each.
+func chartValues(sections, params int) string {
+	var b strings.Builder
+	for s := 0; s < sections; s++ {
+		fmt.Fprintf(&b, "## @section Section %d\n## @descriptionStart\n## Settings of component %d.\n## @descriptionEnd\n", s, s)
+		fmt.Fprintf(&b, "c%d:\n", s)
+		for p := 0; p < params; p++ {
+			fmt.Fprintf(&b, "  ## @param c%d.p%d [string] Parameter %d of component %d\n  p%d: value-%d\n", s, p, p, s, p, p)
+		}
+	}
+	return b.String()
+}
+
+// parsedSections returns the sections of values prepared for rendering.
+func parsedSections(tb testing.TB, values string, cfg *Config) []*Section {
+	tb.Helper()
+	meta, err := getParsedMetadata([]valuesFile{{"values.yaml", []byte(values)}}, nil, cfg)
+	if err != nil {
+		tb.Fatalf("getParsedMetadata: %v", err)
+	}
+	secs, err := renderedSections(meta, cfg)
+	if err != nil {
+		tb.Fatalf("renderedSections: %v", err)
+	}
+	return secs
+}
+
+// renderWithCache renders secs the way the command does with --cache file;
+// an empty file renders without a cache.
+func renderWithCache(tb testing.TB, secs []*Section, file string, cfg *Config) string {
+	tb.Helper()
+	var cache *renderCache
+	if file != "" {
+		cache = loadRenderCache(file, cfg)
+	}
+	md := renderReadmeTable(secs, "###", cfg, cache)
+	if cache != nil {
+		if err := cache.save(file, 0o644); err != nil {
+			tb.Fatalf("saving the cache: %v", err)
+		}
+	}
+	return md
+}
+
+func TestRenderCacheMatchesUncached(t *testing.T) {
+	base := chartValues(3, 4)
+	tests := []struct {
+		name   string
+		values string
+	}{
+		{name: "unchanged", values: base},
+		{name: "changed description", values: strings.Replace(base, "Parameter 2 of component 1", "Changed", 1)},
+		{name: "changed value", values: strings.Replace(base, "p3: value-3", "p3: other", 1)},
+		{name: "section removed", values: chartValues(2, 4)},
+		{name: "section added", values: chartValues(4, 4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			file := filepath.Join(t.TempDir(), "cache.json")
+			renderWithCache(t, parsedSections(t, base, cfg), file, cfg) // fill the cache
+			secs := parsedSections(t, tt.values, cfg)
+			want := renderWithCache(t, secs, "", cfg)
+			if got := renderWithCache(t, secs, file, cfg); got != want {
+				t.Errorf("cached render differs:\n%s\nwant:\n%s", got, want)
+			}
+			// A second run only reads entries written by the first.
+			if got := renderWithCache(t, secs, file, cfg); got != want {
+				t.Errorf("second cached render differs:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+// TestRenderCacheRowFields changes one field that markdownTable reads at a
+// time, with everything else equal, so that a field missing from sectionKey
+// would reuse the stale section.
+func TestRenderCacheRowFields(t *testing.T) {
+	const values = "## @section S\n## @param a A\na: value\n"
+	tests := []struct {
+		name   string
+		change func(p *Parameter)
+	}{
+		{name: "extra", change: func(p *Parameter) { p.Validate = false }},
+		{name: "display value", change: func(p *Parameter) { p.DisplayValue = "shown" }},
+		{name: "literal", change: func(p *Parameter) { p.Literal = "0x1" }},
+		{name: "source", change: func(p *Parameter) { p.Source = `"value"` }},
+		{name: "alias", change: func(p *Parameter) { p.DisplayName = "b" }},
+		{name: "example", change: func(p *Parameter) { p.ExampleLines = []string{"a: 1"} }},
+		{name: "modifier", change: func(p *Parameter) { p.Modifiers = []string{"required"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			file := filepath.Join(t.TempDir(), "cache.json")
+			renderWithCache(t, parsedSections(t, values, cfg), file, cfg) // fill the cache
+			secs := parsedSections(t, values, cfg)
+			tt.change(secs[0].Parameters[0])
+			want := renderWithCache(t, secs, "", cfg)
+			if got := renderWithCache(t, secs, file, cfg); got != want {
+				t.Errorf("cached render differs:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func BenchmarkRenderReadmeTable(b *testing.B) {
+	cfg := DefaultConfig()
+	secs := parsedSections(b, chartValues(50, 40), cfg)
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			renderWithCache(b, secs, "", cfg)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		file := filepath.Join(b.TempDir(), "cache.json")
+		renderWithCache(b, secs, file, cfg)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			renderWithCache(b, secs, file, cfg)
+		}
+	})
+}
+
+func TestReadmeIdempotent(t *testing.T) {
+	const values = "## @section Main\n## @param a A\na: 1\n## @param obj [object, required] Object\nobj:\n  x: 1\n" +
+		"## @section Other\n## @param b B\nb: 2\n"
+	tests := []struct {
+		name   string
+		readme string
+		setup  func(*Config)
+		// contains is a part of the output that the case is about.
+		contains string
+	}{
+		{name: "heading only", readme: "## Parameters\n"},
+		{name: "no trailing newline", readme: "## Parameters"},
+		{name: "section at end of file", readme: "# Chart\n\n## Parameters\n"},
+		{name: "followed by a heading", readme: "# Chart\n\n## Parameters\n\nold\n## License\n\nApache\n"},
+		{name: "blank lines before the next heading", readme: "## Parameters\n\nold\n\n\n\n## License\n"},
+		{name: "stale table", readme: "## Parameters\n\n### Old\n\n| Name | Value |\n| --- | --- |\n| `x` | `1` |\n\n## License\n"},
+		{name: "CRLF", readme: "# Chart\r\n\r\n## Parameters\r\n\r\nold\r\n\r\n## License\r\n"},
+		{name: "blockquote", readme: "> ## Parameters\n>\n> old\n\n## License\n"},
+		{name: "compact", readme: "## Parameters\n\n## License\n", setup: func(cfg *Config) { cfg.Readme.Compact = true }},
+		{name: "summarized values", readme: "## Parameters\n\n## License\n", setup: func(cfg *Config) { cfg.Readme.SummarizeComplexValues = true },
+			contains: "| `obj` | Object      | `{1 key}` |"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			if tt.setup != nil {
+				tt.setup(cfg)
+			}
+			readme := tt.readme
+			var runs []string
+			for i := 0; i < 2; i++ {
+				res, err := Generate(Options{Values: []byte(values), Readme: []byte(readme), Config: cfg})
+				if err != nil {
+					t.Fatalf("Generate: %v", err)
+				}
+				readme = res.Readme
+				runs = append(runs, readme)
+			}
+			if runs[0] != runs[1] {
+				t.Errorf("second run changed the README:\n%q\nfirst run:\n%q", runs[1], runs[0])
+			}
+			if !strings.Contains(runs[0], tt.contains) {
+				t.Errorf("README does not contain %q:\n%s", tt.contains, runs[0])
+			}
+			lines := strings.Split(strings.ReplaceAll(runs[0], "\r\n", "\n"), "\n")
+			for i, l := range lines {
+				if strings.Contains(l, "## Parameters") {
+					if i+2 >= len(lines) || strings.Trim(lines[i+1], "> ") != "" || strings.Trim(lines[i+2], "> ") == "" {
+						t.Errorf("want exactly one blank line below the heading:\n%s", runs[0])
+					}
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestEscapeHTML(t *testing.T) {
+	const values = "## @param tag Use <b>bold</b> & more\ntag: \"<none>\"\n"
+	tests := []struct {
+		escape bool
+		cell   string
+	}{
+		{escape: false, cell: "| Use <b>bold</b> & more |"},
+		{escape: true, cell: "| Use &lt;b&gt;bold&lt;/b&gt; & more |"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.escape), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.EscapeHTML = tt.escape
+			row := tableRow(t, mustGenerate(t, values, cfg).Readme, "tag")
+			if !strings.Contains(row, tt.cell) {
+				t.Errorf("row %q does not contain %q", row, tt.cell)
+			}
+			if !strings.Contains(row, "`<none>`") {
+				t.Errorf("row %q does not keep the value as is", row)
+			}
+		})
+	}
+}
+
+func TestTypeColumn(t *testing.T) {
+	const values = `## @param list [array] List
+list:
+## @param port Port
+port: 80
+## @param ratio Ratio
+ratio: 0.5
+## @param types [type:string|integer] Types
+types: 1
+`
+	tests := []struct {
+		key string
+		typ string
+	}{
+		{"list", "array"},
+		{"port", "integer"},
+		{"ratio", "number"},
+		{"types", "string\\|integer"},
+	}
+	cfg := DefaultConfig()
+	cfg.Readme.Columns = []string{columnName, columnType, columnValue}
+	readme := mustGenerate(t, values, cfg).Readme
+	if !strings.Contains(readme, "| Name ") || !strings.Contains(readme, "| Type ") {
+		t.Fatalf("no Type column:\n%s", readme)
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := tableCells(tableRow(t, readme, tt.key))[1]; got != tt.typ {
+				t.Errorf("type cell = %q, want %q", got, tt.typ)
+			}
+		})
+	}
+}
+
+func TestSummarizeComplexValues(t *testing.T) {
+	const values = `## @param labels [object,nullable] Labels
+labels:
+  a: "1"
+  b: "2"
+  c: "3"
+## @param hosts [array,nullable] Hosts
+hosts: [a, b]
+## @param empty Empty
+empty: {}
+## @param port Port
+port: 80
+`
+	tests := []struct {
+		summarize bool
+		cells     map[string]string
+		details   bool
+	}{
+		{
+			summarize: false,
+			cells:     map[string]string{"labels": "`{\"a\":\"1\",\"b\":\"2\",\"c\":\"3\"}`", "hosts": "`[\"a\",\"b\"]`", "empty": "`{}`", "port": "`80`"},
+		},
+		{
+			summarize: true,
+			cells:     map[string]string{"labels": "`{3 keys}`", "hosts": "`[2 items]`", "empty": "`{}`", "port": "`80`"},
+			details:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.summarize), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.SummarizeComplexValues = tt.summarize
+			readme := mustGenerate(t, values, cfg).Readme
+			for key, want := range tt.cells {
+				if got := tableCells(tableRow(t, readme, key))[2]; got != want {
+					t.Errorf("%s: value %s, want %s", key, got, want)
+				}
+			}
+			if got := strings.Contains(readme, "<details>"); got != tt.details {
+				t.Errorf("details block = %v, want %v:\n%s", got, tt.details, readme)
+			}
+		})
+	}
+}
+
+func TestRowsPerTable(t *testing.T) {
+	values := ""
+	for i := 0; i < 5; i++ {
+		values += fmt.Sprintf("## @param p%d P\np%d: %d\n", i, i, i)
+	}
+	tests := []struct {
+		rows    int
+		headers int
+	}{
+		{rows: 0, headers: 1},
+		{rows: 2, headers: 3},
+		{rows: 5, headers: 1},
+		{rows: 10, headers: 1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.rows), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.RowsPerTable = tt.rows
+			readme := mustGenerate(t, values, cfg).Readme
+			if got := strings.Count(readme, "| Name "); got != tt.headers {
+				t.Errorf("%d header rows, want %d:\n%s", got, tt.headers, readme)
+			}
+			for i := 0; i < 5; i++ {
+				tableRow(t, readme, fmt.Sprintf("p%d", i))
+			}
+		})
+	}
+}
+
+func TestHeaderLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		header string
+	}{
+		{name: "defaults", config: `{}`, header: "| Name | Description | Value |"},
+		{name: "custom", config: `{"readme": {"headers": {"name": "Parameter", "description": "Details", "value": "Default"}}}`,
+			header: "| Parameter | Details | Default |"},
+		{name: "partial", config: `{"readme": {"headers": {"value": "Default"}}}`, header: "| Name | Description | Default |"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"config.json": tt.config})
+			cfg, err := LoadConfig([]string{filepath.Join(dir, "config.json")})
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			readme := mustGenerate(t, "## @param a A\na: 1\n", cfg).Readme
+			if !strings.Contains(readme, tt.header) {
+				t.Errorf("README has no header %q:\n%s", tt.header, readme)
+			}
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	const values = "## @section Web\n## @param a A\na: 1\n## @section DB\n## @param b B\nb: 2\n"
+	tests := []struct {
+		compact bool
+		want    []string
+	}{
+		{compact: false, want: []string{
+			"## Parameters",
+			"",
+			"### Web",
+			"",
+			"| Name | Description | Value |",
+			"| ---- | ----------- | ----- |",
+			"| `a`  | A           | `1`   |",
+			"",
+			"### DB",
+			"",
+			"| Name | Description | Value |",
+			"| ---- | ----------- | ----- |",
+			"| `b`  | B           | `2`   |",
+		}},
+		{compact: true, want: []string{
+			"## Parameters",
+			"",
+			"| Section | Name | Description | Value |",
+			"| ------- | ---- | ----------- | ----- |",
+			"| Web     | `a`  | A           | `1`   |",
+			"| DB      | `b`  | B           | `2`   |",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.compact), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.Compact = tt.compact
+			res, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Config: cfg})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if want := strings.Join(tt.want, "\n") + "\n"; res.Readme != want {
+				t.Errorf("README:\n%s\nwant:\n%s", res.Readme, want)
+			}
+		})
+	}
+}
+
+func TestRequiredPlaceholder(t *testing.T) {
+	const values = "## @param a [required] A\na:\n## @param b B\nb:\n## @param c [required] C\nc: x\n## @param d [nullable] D\nd:\n"
+	tests := []struct {
+		name        string
+		placeholder string
+		cells       map[string]string
+	}{
+		{
+			name:        "default",
+			placeholder: "<must be set>",
+			cells:       map[string]string{"a": "`<must be set>`", "b": "`null`", "c": "`x`", "d": "`nil`"},
+		},
+		{
+			name:        "custom",
+			placeholder: "REQUIRED",
+			cells:       map[string]string{"a": "`REQUIRED`", "b": "`null`", "c": "`x`"},
+		},
+		{
+			name:  "disabled",
+			cells: map[string]string{"a": "`null`", "b": "`null`", "c": "`x`"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.RequiredPlaceholder = tt.placeholder
+			readme := mustGenerate(t, values, cfg).Readme
+			for key, want := range tt.cells {
+				if got := tableCells(tableRow(t, readme, key))[2]; got != want {
+					t.Errorf("%s: value cell %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMaxTableWidth(t *testing.T) {
+	// Rows of "| `a`  | <desc> | `1`   |" are 19 characters wider than desc
+	// when it is wider than the "Description" header.
+	tests := []struct {
+		name  string
+		desc  string
+		limit int
+		warn  string
+	}{
+		{name: "disabled", desc: strings.Repeat("x", 100)},
+		{name: "within", desc: "Replicas to run here", limit: 39},
+		{name: "too wide", desc: "Replicas to run here", limit: 38, warn: `section "Values" is 39 characters wide (limit 38)`},
+		{name: "non-ASCII within", desc: "Réplicas à exécuter", limit: 38},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.MaxTableWidth = tt.limit
+			var log strings.Builder
+			res, err := Generate(Options{Values: []byte("## @section Values\n## @param a " + tt.desc + "\na: 1\n"),
+				Readme: []byte(readmeHeading), Config: cfg, Log: &log})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			want := 0
+			if tt.warn != "" {
+				want = 1
+			}
+			if res.Warnings != want || !strings.Contains(log.String(), tt.warn) {
+				t.Errorf("Warnings = %d, log %q; want %d, %q", res.Warnings, log.String(), want, tt.warn)
+			}
+		})
+	}
+}
+
+func TestExampleCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		want   string
+	}{
+		{
+			name:   "multiline",
+			values: "## @param ingress.hosts [array,example-code:helm install app . --set ingress.hosts[0]=a.example.com\\nkubectl get ingress] Hosts\ningress:\n  hosts: []\n",
+			want:   "Example for `ingress.hosts`:\n\n```\nhelm install app . --set ingress.hosts[0]=a.example.com\nkubectl get ingress\n```\n",
+		},
+		{
+			name:   "commas",
+			values: "## @param args [array,example-code:--set args={a, b}] Args\nargs: []\n",
+			want:   "Example for `args`:\n\n```\n--set args={a, b}\n```\n",
+		},
+		{
+			name:   "no example",
+			values: "## @param args [array] Args\nargs: []\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readme := mustGenerate(t, tt.values, nil).Readme
+			examples := strings.TrimPrefix(readme[strings.LastIndex(readme, "|\n")+2:], "\n")
+			if examples != tt.want {
+				t.Errorf("after the table:\n%s\nwant:\n%s", examples, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnchorPrefix(t *testing.T) {
+	const values = "## @section Common parameters\n## @param a A\na: 1\n## @section # Ingress TLS\n## @param b B\nb: 1\n"
+	tests := []struct {
+		name    string
+		prefix  string
+		compact bool
+		rows    bool
+		anchors []string
+	}{
+		{name: "none"},
+		{name: "rows", rows: true, anchors: []string{"| <a id=\"a\"></a>`a` ", "| <a id=\"b\"></a>`b` "}},
+		{name: "prefix", prefix: "params-", anchors: []string{
+			"<a id=\"params-common-parameters\"></a>\n\n### Common parameters\n",
+			"<a id=\"params-ingress-tls\"></a>\n\n#### Ingress TLS\n",
+		}},
+		{name: "prefixed rows", prefix: "params-", rows: true, anchors: []string{
+			"<a id=\"params-common-parameters\"></a>\n\n### Common parameters\n",
+			"<a id=\"params-ingress-tls\"></a>\n\n#### Ingress TLS\n",
+			"| <a id=\"params-a\"></a>`a` ",
+			"| <a id=\"params-b\"></a>`b` ",
+		}},
+		{name: "compact", prefix: "params-", compact: true},
+		{name: "compact rows", prefix: "params-", compact: true, rows: true, anchors: []string{
+			"| Common parameters | <a id=\"params-a\"></a>`a` ",
+			"| Ingress TLS       | <a id=\"params-b\"></a>`b` ",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.AnchorPrefix = tt.prefix
+			cfg.Readme.Compact = tt.compact
+			cfg.Readme.RowAnchors = tt.rows
+			res, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Config: cfg})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if got := strings.Count(res.Readme, "<a id="); got != len(tt.anchors) {
+				t.Errorf("%d anchors, want %d:\n%s", got, len(tt.anchors), res.Readme)
+			}
+			for _, a := range tt.anchors {
+				if !strings.Contains(res.Readme, a) {
+					t.Errorf("README does not contain %q:\n%s", a, res.Readme)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplateModifier(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		note   string
+		desc   string
+		hint   interface{}
+	}{
+		{
+			name:   "note appended",
+			values: "## @param annotations [template] Annotations\nannotations: \"{{ .Release.Name }}\"\n",
+			note:   "Supports templating (`{{ ... }}`).",
+			desc:   "Annotations. Supports templating (`{{ ... }}`).",
+			hint:   true,
+		},
+		{
+			name:   "sentence already ended",
+			values: "## @param annotations [template] Annotations.\nannotations: x\n",
+			note:   "Supports templating (`{{ ... }}`).",
+			desc:   "Annotations. Supports templating (`{{ ... }}`).",
+			hint:   true,
+		},
+		{
+			name:   "no note",
+			values: "## @param annotations [template] Annotations\nannotations: x\n",
+			desc:   "Annotations",
+			hint:   true,
+		},
+		{
+			name:   "plain value",
+			values: "## @param annotations Annotations\nannotations: x\n",
+			note:   "Supports templating (`{{ ... }}`).",
+			desc:   "Annotations",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.TemplateNote = tt.note
+			res := mustGenerate(t, tt.values, cfg)
+			if got := tableCells(tableRow(t, res.Readme, "annotations"))[1]; got != tt.desc {
+				t.Errorf("description %q, want %q", got, tt.desc)
+			}
+			if got := property(t, res.Schema, "annotations")["x-helm-template"]; got != tt.hint {
+				t.Errorf("x-helm-template = %v, want %v", got, tt.hint)
+			}
+		})
+	}
+}
+
+func TestInclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"docs/note.md":  "Storage note.\n@include more.md\n",
+		"docs/more.md":  "More.\n",
+		"cycle/a.md":    "A\n@include b.md\n",
+		"cycle/b.md":    "@include a.md\n",
+		"cycle/self.md": "@include self.md\n",
+	})
+	tests := []struct {
+		name    string
+		include string
+		desc    string
+		err     string
+	}{
+		{name: "nested", include: "docs/note.md", desc: "Intro\nStorage note.\nMore.\n"},
+		{name: "missing", include: "docs/missing.md", err: "line 4: include: open "},
+		{name: "cycle", include: "cycle/a.md", err: "line 4: include cycle: " + filepath.Join(dir, "cycle/a.md") + " -> " +
+			filepath.Join(dir, "cycle/b.md") + " -> " + filepath.Join(dir, "cycle/a.md")},
+		{name: "self", include: "cycle/self.md", err: "include cycle: " + filepath.Join(dir, "cycle/self.md") + " -> " +
+			filepath.Join(dir, "cycle/self.md")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := "## @section Persistence\n## @descriptionStart\n## Intro\n## @include " + tt.include +
+				"\n## @descriptionEnd\n## @param a A\na: 1\n"
+			res, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Dir: dir})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if want := "### Persistence\n\n" + tt.desc + "\n| Name"; !strings.Contains(res.Readme, want) {
+				t.Errorf("README does not contain %q:\n%s", want, res.Readme)
+			}
+		})
+	}
+}
+
+func TestEmptySection(t *testing.T) {
+	const values = `## @section Future
+## @descriptionStart
+## Coming soon.
+## @descriptionEnd
+## @section Parent
+## @section # Child
+## @param b B
+b: 1
+## @section Last
+`
+	const table = "| Name | Description | Value |\n| ---- | ----------- | ----- |\n| `b`  | B           | `1`   |\n"
+	tests := []struct {
+		name        string
+		placeholder string
+		want        string
+	}{
+		{
+			name: "no placeholder",
+			want: "## Parameters\n\n### Future\n\nComing soon.\n\n### Parent\n\n#### Child\n\n" + table + "\n### Last\n",
+		},
+		{
+			name:        "placeholder",
+			placeholder: "_No configurable parameters._",
+			want: "## Parameters\n\n### Future\n\nComing soon.\n\n_No configurable parameters._\n\n### Parent\n\n#### Child\n\n" + table +
+				"\n### Last\n\n_No configurable parameters._\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.EmptySection = tt.placeholder
+			res, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Config: cfg})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if res.Readme != tt.want {
+				t.Errorf("README:\n%s\nwant:\n%s", res.Readme, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndentedParametersRegion(t *testing.T) {
+	const table = "### S\n\n| Name | Description | Value |\n| ---- | ----------- | ----- |\n| `a`  | A           | `1`   |\n"
+	indent := func(prefix, blank, s string) string {
+		var b strings.Builder
+		for _, l := range strings.SplitAfter(strings.TrimSuffix(s, "\n"), "\n") {
+			if l == "\n" {
+				b.WriteString(blank + "\n")
+				continue
+			}
+			b.WriteString(prefix + l)
+		}
+		return b.String() + "\n"
+	}
+	tests := []struct {
+		name   string
+		readme string
+		want   string
+	}{
+		{
+			name:   "top level",
+			readme: "# Chart\n\n## Parameters\n\nold\n",
+			want:   "# Chart\n\n## Parameters\n\n" + table,
+		},
+		{
+			name:   "list item",
+			readme: "- Docs\n  ## Parameters\n\n  old\n- Next item\n",
+			want:   "- Docs\n  ## Parameters\n\n" + indent("  ", "", table) + "\n- Next item\n",
+		},
+		{
+			name:   "blockquote",
+			readme: "> ## Parameters\n>\n> old\n\nAfter\n",
+			want:   "> ## Parameters\n>\n" + indent("> ", ">", table) + "\nAfter\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Generate(Options{Values: []byte("## @section S\n## @param a A\na: 1\n"), Readme: []byte(tt.readme)})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if res.Readme != tt.want {
+				t.Errorf("README:\n%s\nwant:\n%s", res.Readme, tt.want)
+			}
+			again, err := Generate(Options{Values: []byte("## @section S\n## @param a A\na: 1\n"), Readme: []byte(res.Readme)})
+			if err != nil || again.Readme != res.Readme {
+				t.Errorf("second run changed the README (error %v):\n%s", err, again.Readme)
+			}
+		})
+	}
+}
+
+func TestTableStyle(t *testing.T) {
+	const values = "## @param a A\na: 1\n## @param b.long.name B\nb:\n  long:\n    name: x\n"
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{style: tableStylePadded, want: "| Name          | Description | Value |\n" +
+			"| ------------- | ----------- | ----- |\n" +
+			"| `a`           | A           | `1`   |\n" +
+			"| `b.long.name` | B           | `x`   |\n"},
+		{style: tableStyleCompact, want: "| Name | Description | Value |\n" +
+			"| --- | --- | --- |\n" +
+			"| `a` | A | `1` |\n" +
+			"| `b.long.name` | B | `x` |\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.TableStyle = tt.style
+			readme := mustGenerate(t, values, cfg).Readme
+			if table := readme[strings.Index(readme, "| Name"):]; table != tt.want {
+				t.Errorf("table:\n%s\nwant:\n%s", table, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequiredColumn(t *testing.T) {
+	const values = "## @param a [required] A\na: 1\n## @param b B\nb: 2\n"
+	tests := []struct {
+		name       string
+		typeColumn bool
+		label      string
+		want       string
+	}{
+		{name: "after name", want: "| Name | Required | Description | Value |\n" +
+			"| ---- | -------- | ----------- | ----- |\n" +
+			"| `a`  | ✓        | A           | `1`   |\n" +
+			"| `b`  |          | B           | `2`   |\n"},
+		{name: "after type", typeColumn: true, want: "| Name | Type    | Required | Description | Value |\n" +
+			"| ---- | ------- | -------- | ----------- | ----- |\n" +
+			"| `a`  | integer | ✓        | A           | `1`   |\n" +
+			"| `b`  | integer |          | B           | `2`   |\n"},
+		{name: "custom label", label: "Req.", want: "| Name | Req. | Description | Value |\n" +
+			"| ---- | ---- | ----------- | ----- |\n" +
+			"| `a`  | ✓    | A           | `1`   |\n" +
+			"| `b`  |      | B           | `2`   |\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.RequiredColumn = true
+			cfg.Readme.TypeColumn = tt.typeColumn
+			if tt.label != "" {
+				cfg.Readme.Headers.Required = tt.label
+			}
+			readme := mustGenerate(t, values, cfg).Readme
+			if table := readme[strings.Index(readme, "| Name"):]; table != tt.want {
+				t.Errorf("table:\n%s\nwant:\n%s", table, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigitSeparator(t *testing.T) {
+	tests := []struct {
+		value     string
+		separator string
+		shown     string
+	}{
+		{value: "10000", separator: ",", shown: "`10,000`"},
+		{value: "-1234567", separator: ",", shown: "`-1,234,567`"},
+		{value: "123456", separator: "_", shown: "`123_456`"},
+		{value: "8080", separator: ",", shown: "`8080`"},
+		{value: "-1234", separator: ",", shown: "`-1234`"},
+		{value: "12345.5", separator: ",", shown: "`12345.5`"},
+		{value: "\"12345\"", separator: ",", shown: "`12345`"},
+		{value: "10000", shown: "`10000`"},
+		{value: "True", separator: ",", shown: "`true`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value+tt.separator, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.DigitSeparator = tt.separator
+			res := mustGenerate(t, "## @param n N\nn: "+tt.value+"\n", cfg)
+			if got := tableCells(tableRow(t, res.Readme, "n"))[2]; got != tt.shown {
+				t.Errorf("value %s, want %s", got, tt.shown)
+			}
+		})
+	}
+
+	cfg := DefaultConfig()
+	cfg.Readme.DigitSeparator = ","
+	res := mustGenerate(t, "## @param n N\nn: 10000\n", cfg)
+	if dflt := property(t, res.Schema, "n")["default"]; !jsonEqual(dflt, 10000) {
+		t.Errorf("schema default %v, want 10000", dflt)
+	}
+}
+
+func TestNestedSections(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   string
+		headings []string
+	}{
+		{
+			name:     "nested",
+			values:   "## @section Database\n## @param a A\na: 1\n## @section # Pool\n## @param b B\nb: 1\n## @section ## Limits\n## @param c C\nc: 1\n",
+			headings: []string{"### Database", "#### Pool", "##### Limits"},
+		},
+		{
+			name:     "back to the top",
+			values:   "## @section A\n## @param a A\na: 1\n## @section # Sub\n## @param b B\nb: 1\n## @section B\n## @param c C\nc: 1\n",
+			headings: []string{"### A", "#### Sub", "### B"},
+		},
+		{
+			name:     "capped",
+			values:   "## @section Top\n## @param a A\na: 1\n## @section ####### Deep\n## @param b B\nb: 1\n",
+			headings: []string{"### Top", "###### Deep"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Generate(Options{Values: []byte(tt.values), Readme: []byte(readmeHeading)})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			var headings []string
+			for _, line := range strings.Split(res.Readme, "\n") {
+				if strings.HasPrefix(line, "###") {
+					headings = append(headings, line)
+				}
+			}
+			if strings.Join(headings, "\n") != strings.Join(tt.headings, "\n") {
+				t.Errorf("headings %q, want %q", headings, tt.headings)
+			}
+		})
+	}
+}
+
+func TestColumns(t *testing.T) {
+	const values = "## @param a [required] A\na: 1\n"
+	tests := []struct {
+		name       string
+		columns    []string
+		typeColumn bool
+		header     string
+		row        string
+		err        string
+	}{
+		{name: "reordered", columns: []string{"Value", "Name"}, header: "| Value | Name |", row: "| `1`   | `a`  |"},
+		{name: "all", columns: []string{"Name", "Value", "Type", "Required", "Description"},
+			header: "| Name | Value | Type    | Required | Description |", row: "| `a`  | `1`   | integer | ✓        | A           |"},
+		{name: "without value", columns: []string{"Name", "Description"}, header: "| Name | Description |", row: "| `a`  | A           |"},
+		{name: "typeColumn after name", columns: []string{"Value", "Name"}, typeColumn: true,
+			header: "| Value | Name | Type    |", row: "| `1`   | `a`  | integer |"},
+		{name: "unknown", columns: []string{"Name", "Bogus"}, err: `invalid readme.columns entry "Bogus"`},
+		{name: "repeated", columns: []string{"Name", "Name"}, err: `readme.columns lists "Name" twice`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.Columns = tt.columns
+			cfg.Readme.TypeColumn = tt.typeColumn
+			res, err := Generate(Options{Values: []byte("## @section S\n" + values), Readme: []byte(readmeHeading), Config: cfg})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			for _, line := range []string{tt.header, tt.row} {
+				if !strings.Contains(res.Readme, "\n"+line+"\n") {
+					t.Errorf("README has no line %q:\n%s", line, res.Readme)
+				}
+			}
+		})
+	}
+}
+
+func TestSectionSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		desc    string
+		enabled bool
+		want    string
+	}{
+		{name: "first sentence", desc: "First one. Second one.", enabled: true, want: "*First one.*\n\nFirst one. Second one.\n\n"},
+		{name: "question mark", desc: "Is it? Yes.", enabled: true, want: "*Is it?*\n\nIs it? Yes.\n\n"},
+		{name: "no mark", desc: "No mark at all", enabled: true, want: "*No mark at all*\n\nNo mark at all\n\n"},
+		{name: "dot inside a word", desc: "Version 1.2 is used. Then", enabled: true, want: "*Version 1.2 is used.*\n\nVersion 1.2 is used. Then\n\n"},
+		{name: "line break", desc: "Line one\n## continues here. Rest", enabled: true,
+			want: "*Line one continues here.*\n\nLine one\ncontinues here. Rest\n\n"},
+		{name: "disabled", desc: "First one. Second one.", want: "First one. Second one.\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.SectionSummary = tt.enabled
+			values := "## @section S\n## @descriptionStart\n## " + tt.desc + "\n## @descriptionEnd\n## @param a A\na: 1\n"
+			res, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Config: cfg})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if want := "### S\n\n" + tt.want + "| Name"; !strings.Contains(res.Readme, want) {
+				t.Errorf("README:\n%s\nwant:\n%s", res.Readme, want)
+			}
+		})
+	}
+
+	res, err := Generate(Options{Values: []byte("## @section S\n## @param a A\na: 1\n"), Readme: []byte(readmeHeading),
+		Config: func() *Config { c := DefaultConfig(); c.Readme.SectionSummary = true; return c }()})
+	if err != nil || !strings.Contains(res.Readme, "### S\n\n| Name") {
+		t.Errorf("a section without a description got a summary (error %v):\n%s", err, res.Readme)
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	const values = "## @section S\n## @descriptionStart\n## Desc one\n## two\n## @descriptionEnd\n## @param a A\na: 1\n"
+	const table = "### S\n\nDesc one\ntwo\n\n| Name | Description | Value |\n| ---- | ----------- | ----- |\n| `a`  | A           | `1`   |\n"
+	crlf := func(s string) string { return strings.ReplaceAll(s, "\n", "\r\n") }
+	tests := []struct {
+		name   string
+		readme string
+		want   string
+	}{
+		{name: "LF", readme: "# Chart\n\n## Parameters\n\nold\n\n## License\n",
+			want: "# Chart\n\n## Parameters\n\n" + table + "\n## License\n"},
+		{name: "CRLF", readme: crlf("# Chart\n\n## Parameters\n\nold\n\n## License\n"),
+			want: crlf("# Chart\n\n## Parameters\n\n" + table + "\n## License\n")},
+		{name: "CRLF at end of file", readme: crlf("# Chart\n\n## Parameters\n"),
+			want: crlf("# Chart\n\n## Parameters\n\n" + table)},
+		{name: "mostly CRLF", readme: crlf("# Chart\n\nIntro\n\n") + "## Parameters\n",
+			want: crlf("# Chart\n\nIntro\n\n## Parameters\n\n" + table)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Generate(Options{Values: []byte(values), Readme: []byte(tt.readme)})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if res.Readme != tt.want {
+				t.Errorf("README:\n%q\nwant:\n%q", res.Readme, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		skip    string
+		compact bool
+		want    string
+	}{
+		{name: "reason", skip: "image set by the release pipeline", want: "<!-- skipped image: set by the release pipeline -->\n"},
+		{name: "no reason", skip: "image"},
+		{name: "comment end", skip: "image see --> here", want: "<!-- skipped image: see - -> here -->\n"},
+		{name: "dashes", skip: "image a---b", want: "<!-- skipped image: a- - -b -->\n"},
+		{name: "compact", skip: "image set by the release pipeline", compact: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.Compact = tt.compact
+			values := "## @section S\n## @param a A\na: 1\n## @skip " + tt.skip + "\nimage:\n  digest: x\n## @section T\n## @param b B\nb: 1\n"
+			res, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Config: cfg})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if tt.want == "" {
+				if strings.Contains(res.Readme, "<!--") {
+					t.Errorf("README has a skip comment:\n%s", res.Readme)
+				}
+				return
+			}
+			if want := "| `a`  | A           | `1`   |\n\n" + tt.want + "\n### T\n"; !strings.Contains(res.Readme, want) {
+				t.Errorf("README:\n%s\nwant:\n%s", res.Readme, want)
+			}
+		})
+	}
+}
+
+func TestParamSort(t *testing.T) {
+	const values = "## @section Two\n## @param zeta Z\nzeta: 1\n## @param alpha A\nalpha: 2\n## @param mid M\nmid: 3\n" +
+		"## @section One\n## @param y Y\ny: 1\n## @param b B\nb: 2\n"
+	tests := []struct {
+		sort string
+		rows []string
+	}{
+		{sort: "", rows: []string{"zeta", "alpha", "mid", "y", "b"}},
+		{sort: "file", rows: []string{"zeta", "alpha", "mid", "y", "b"}},
+		{sort: "alpha", rows: []string{"alpha", "mid", "zeta", "b", "y"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			cfg := DefaultConfig()
+			if tt.sort != "" {
+				cfg.Readme.ParamSort = tt.sort
+			}
+			res, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Config: cfg})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			var rows []string
+			for _, line := range strings.Split(res.Readme, "\n") {
+				if strings.HasPrefix(line, "| `") {
+					rows = append(rows, strings.Trim(strings.Fields(line)[1], "`"))
+				}
+			}
+			if strings.Join(rows, ",") != strings.Join(tt.rows, ",") {
+				t.Errorf("rows %v, want %v", rows, tt.rows)
+			}
+			if strings.Index(res.Readme, "### Two") > strings.Index(res.Readme, "### One") {
+				t.Errorf("sections reordered:\n%s", res.Readme)
+			}
+		})
+	}
+
+	t.Run("params-json keeps file order", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{"values.yaml": values})
+		captureLog(t)
+		out := filepath.Join(dir, "params.json")
+		if err := runReadmeGenerator(&options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")}, paramsJSONPath: out,
+			paramSort: "alpha"}); err != nil {
+			t.Fatalf("runReadmeGenerator: %v", err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var params []struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(data, &params); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, p := range params {
+			names = append(names, p.Name)
+		}
+		if want := "zeta,alpha,mid,y,b"; strings.Join(names, ",") != want {
+			t.Errorf("params %v, want %s", names, want)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Readme.ParamSort = "name"
+		if _, err := Generate(Options{Values: []byte(values), Readme: []byte(readmeHeading), Config: cfg}); err == nil ||
+			!strings.Contains(err.Error(), `invalid readme.paramSort "name"`) {
+			t.Errorf("error %v, want invalid readme.paramSort", err)
+		}
+		dir := writeFiles(t, map[string]string{"values.yaml": values})
+		captureLog(t)
+		if err := runReadmeGenerator(&options{valuesPaths: stringList{filepath.Join(dir, "values.yaml")}, paramSort: "name"}); err == nil ||
+			!strings.Contains(err.Error(), `invalid --param-sort "name"`) {
+			t.Errorf("error %v, want invalid --param-sort", err)
+		}
+	})
+}
+
+func TestRowAnchors(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  string
+		prefix  string
+		anchors map[string]string
+		log     string
+	}{
+		{
+			name:    "anchors",
+			values:  "## @section Image\n## @param image.tag Tag\n## @param hosts[0] Host\nimage:\n  tag: v1\nhosts:\n  - a\n",
+			anchors: map[string]string{"image.tag": "image-tag", "hosts[0]": "hosts0"},
+		},
+		{
+			name:    "prefix",
+			values:  "## @section Image\n## @param image.tag Tag\nimage:\n  tag: v1\n",
+			prefix:  "params-",
+			anchors: map[string]string{"image.tag": "params-image-tag"},
+		},
+		{
+			name:    "alias",
+			values:  "## @section Image\n## @param internal.ref @alias image.repository Repository\ninternal:\n  ref: nginx\n",
+			anchors: map[string]string{"image.repository": "image-repository"},
+		},
+		{
+			name:    "colliding keys",
+			values:  "## @section S\n## @param a.b A\n## @param a-b B\na:\n  b: 1\na-b: 2\n",
+			anchors: map[string]string{"a.b": "a-b", "a-b": "a-b"},
+			log:     `values.yaml:3: WARNING: parameter "a-b" shares the anchor #a-b with parameter "a.b"`,
+		},
+		{
+			name:    "key colliding with a section",
+			values:  "## @section Image tag\n## @param image.tag Tag\nimage:\n  tag: v1\n",
+			anchors: map[string]string{"image.tag": "image-tag"},
+			log:     `WARNING: parameter "image.tag" shares the anchor #image-tag with section "Image tag"`,
+		},
+		{
+			name:    "prefixed section",
+			values:  "## @section Image tag\n## @param image.tag Tag\nimage:\n  tag: v1\n",
+			prefix:  "p-",
+			anchors: map[string]string{"image.tag": "p-image-tag"},
+			log:     `WARNING: parameter "image.tag" shares the anchor #p-image-tag with section "Image tag"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Readme.RowAnchors = true
+			cfg.Readme.AnchorPrefix = tt.prefix
+			var log strings.Builder
+			res, err := Generate(Options{Values: []byte(tt.values), Readme: []byte(readmeHeading), Config: cfg, Log: &log})
+			if err != nil {
+				t.Fatalf("Generate: %v\n%s", err, log.String())
+			}
+			for name, anchor := range tt.anchors {
+				want := fmt.Sprintf("| <a id=%q></a>`%s` ", anchor, name)
+				if !strings.Contains(res.Readme, want) {
+					t.Errorf("no row starting with %q:\n%s", want, res.Readme)
+				}
+			}
+			if tt.log == "" && strings.Contains(log.String(), "shares the anchor") || !strings.Contains(log.String(), tt.log) {
+				t.Errorf("log %q, want %q", log.String(), tt.log)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		var log strings.Builder
+		res, err := Generate(Options{Values: []byte("## @section S\n## @param a.b A\n## @param a-b B\na:\n  b: 1\na-b: 2\n"),
+			Readme: []byte(readmeHeading), Config: DefaultConfig(), Log: &log})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(res.Readme, "<a id=") || strings.Contains(log.String(), "shares the anchor") {
+			t.Errorf("anchors without readme.rowAnchors:\n%s\n%s", res.Readme, log.String())
+		}
+	})
+}
+
+func TestAlias(t *testing.T) {
+	const nested = "internal:\n  v2:\n    imageRef: nginx\n"
+	tests := []struct {
+		name   string
+		values string
+		row    string
+		desc   string
+		typ    string
+	}{
+		{name: "alias", values: "## @param internal.v2.imageRef @alias image.repository Image repository\n" + nested,
+			row: "image.repository", desc: "Image repository", typ: "string"},
+		{name: "with modifiers", values: "## @param internal.v2.imageRef @alias image.repository [nullable] Image repository\n" + nested,
+			row: "image.repository", desc: "Image repository", typ: "string"},
+		{name: "no alias", values: "## @param internal.v2.imageRef Image repository\n" + nested,
+			row: "internal.v2.imageRef", desc: "Image repository", typ: "string"},
+		{name: "alias inside the description", values: "## @param internal.v2.imageRef See @alias docs\n" + nested,
+			row: "internal.v2.imageRef", desc: "See @alias docs", typ: "string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := mustGenerate(t, tt.values, DefaultConfig())
+			if got := tableCells(tableRow(t, res.Readme, tt.row))[1]; got != tt.desc {
+				t.Errorf("description %q, want %q", got, tt.desc)
+			}
+			if tt.row != "internal.v2.imageRef" && containsRow(res.Readme, "internal.v2.imageRef") {
+				t.Errorf("README shows the real key:\n%s", res.Readme)
+			}
+			if got := property(t, res.Schema, "internal.v2.imageRef")["type"]; got != tt.typ {
+				t.Errorf("schema type %v, want %s", got, tt.typ)
+			}
+			if strings.Contains(string(res.Schema), `"repository"`) {
+				t.Errorf("schema uses the alias:\n%s", res.Schema)
+			}
+		})
+	}
+
+	t.Run("sort and examples use the alias", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Readme.ParamSort = "alpha"
+		res := mustGenerate(t, "## @param z @alias a.first [example-code:z: 1] Z\nz: 1\n## @param b B\nb: 2\n", cfg)
+		if strings.Index(res.Readme, "`a.first`") > strings.Index(res.Readme, "`b`") {
+			t.Errorf("alias not sorted by its name:\n%s", res.Readme)
+		}
+		if !strings.Contains(res.Readme, "Example for `a.first`:") {
+			t.Errorf("example not named after the alias:\n%s", res.Readme)
+		}
+	})
+
+	t.Run("keys are checked by the real name", func(t *testing.T) {
+		var log strings.Builder
+		_, err := Generate(Options{Values: []byte("## @section S\n## @param a @alias shown A\nshown: 1\n"), Schema: true,
+			Config: DefaultConfig(), Log: &log})
+		if err == nil || !strings.Contains(log.String(), "Missing metadata for key: shown") ||
+			!strings.Contains(log.String(), "Metadata provided for non existing key: a") {
+			t.Errorf("error %v, log:\n%s", err, log.String())
+		}
+	})
+
+	t.Run("custom tag", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Tags.Alias = "@as"
+		res := mustGenerate(t, "## @param internal.v2.imageRef @as image.repository Image repository\n"+nested, cfg)
+		if !containsRow(res.Readme, "image.repository") {
+			t.Errorf("README:\n%s", res.Readme)
+		}
+	})
+}
+
+func TestPreserveLiterals(t *testing.T) {
+	tests := []struct {
+		value  string
+		on     string
+		off    string
+		schema interface{}
+	}{
+		{value: "0x1F", on: "`0x1F`", off: "`31`", schema: float64(31)},
+		{value: "-0x10", on: "`-0x10`", off: "`-16`", schema: float64(-16)},
+		{value: "0o17", on: "`0o17`", off: "`15`", schema: float64(15)},
+		{value: "1_000", on: "`1_000`", off: "`1000`", schema: float64(1000)},
+		{value: "1_000.5", on: "`1_000.5`", off: "`1000.5`", schema: 1000.5},
+		{value: "1.0", on: "`1.0`", off: "`1`", schema: float64(1)},
+		{value: "3.10", on: "`3.10`", off: "`3.1`", schema: 3.1},
+		{value: "42", on: "`42`", off: "`42`", schema: float64(42)},
+		{value: `"3.10"`, on: "`\"3.10\"`", off: "`3.10`", schema: "3.10"},
+		{value: `'42'`, on: "`'42'`", off: "`42`", schema: "42"},
+		{value: `'true'`, on: "`'true'`", off: "`true`", schema: "true"},
+		{value: `"null"`, on: "`\"null\"`", off: "`null`", schema: "null"},
+		{value: `"hello"`, on: "`hello`", off: "`hello`", schema: "hello"},
+		{value: `"yes"`, on: "`yes`", off: "`yes`", schema: "yes"},
+		{value: `""`, on: "`\"\"`", off: "`\"\"`", schema: ""},
+	}
+	for _, tt := range tests {
+		for _, on := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/%t", tt.value, on), func(t *testing.T) {
+				cfg := DefaultConfig()
+				cfg.Readme.PreserveLiterals = on
+				res := mustGenerate(t, "## @param p P\np: "+tt.value+"\n", cfg)
+				want := tt.off
+				if on {
+					want = tt.on
+				}
+				if got := tableCells(tableRow(t, res.Readme, "p"))[2]; got != want {
+					t.Errorf("README value %s, want %s", got, want)
+				}
+				if got := property(t, res.Schema, "p")["default"]; got != tt.schema {
+					t.Errorf("schema default %#v, want %#v", got, tt.schema)
+				}
+			})
+		}
+	}
+
+	t.Run("replaced by a modifier", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Readme.PreserveLiterals = true
+		res := mustGenerate(t, "## @param p [default:16] P\np: 0x1F\n", cfg)
+		if got := tableCells(tableRow(t, res.Readme, "p"))[2]; got != "`16`" {
+			t.Errorf("README value %s, want `16`", got)
+		}
+	})
+}
+
+func TestExampleBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   string
+		examples string
+		err      string
+	}{
+		{
+			name:     "indentation kept",
+			values:   "## @param hosts [array] Hosts\n## @example\n## hosts:\n##   - host: a\n##     paths: [\"/\"]\n## @exampleEnd\nhosts: []\n",
+			examples: "#### `hosts`\n\n```yaml\nhosts:\n  - host: a\n    paths: [\"/\"]\n```\n",
+		},
+		{
+			name:     "repeated blocks",
+			values:   "## @param hosts [array] Hosts\n## @example\n## hosts: [a]\n## @exampleEnd\n## @example hosts: []\n## @exampleEnd\nhosts: []\n",
+			examples: "#### `hosts`\n\n```yaml\nhosts: [a]\n\nhosts: []\n```\n",
+		},
+		{
+			name: "one per parameter",
+			values: "## @param a A\n## @example\n## a: 1\n## @exampleEnd\na: 0\n## @param b B\nb: 0\n" +
+				"## @param c @alias shown C\n## @example\n## c: 2\n## @exampleEnd\nc: 0\n",
+			examples: "#### `a`\n\n```yaml\na: 1\n```\n\n#### `shown`\n\n```yaml\nc: 2\n```\n",
+		},
+		{
+			name:   "without a parameter",
+			values: "## @example\n## a: 1\n## @exampleEnd\n## @param a A\na: 0\n",
+			err:    "values.yaml:2: @example without a preceding @param",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Generate(Options{Values: []byte("## @section Values\n" + tt.values), Readme: []byte(readmeHeading),
+				Config: DefaultConfig()})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			i := strings.Index(res.Readme, "\n####")
+			if i < 0 {
+				t.Fatalf("no examples:\n%s", res.Readme)
+			}
+			if got := res.Readme[i+1:]; got != tt.examples {
+				t.Errorf("examples:\n%s\nwant:\n%s", got, tt.examples)
+			}
+		})
+	}
+
+	t.Run("compact", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Readme.Compact = true
+		res := mustGenerate(t, "## @param a A\n## @example\n## a: 1\n## @exampleEnd\na: 0\n", cfg)
+		if !strings.HasSuffix(res.Readme, "|\n\n### `a`\n\n```yaml\na: 1\n```\n") {
+			t.Errorf("README:\n%s", res.Readme)
+		}
+	})
+
+	t.Run("custom tags", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Tags.ExampleStart, cfg.Tags.ExampleEnd = "@sample", "@end"
+		res := mustGenerate(t, "## @param a A\n## @sample\n## a: 1\n## @end\na: 0\n", cfg)
+		if !strings.Contains(res.Readme, "#### `a`\n\n```yaml\na: 1\n```\n") {
+			t.Errorf("README:\n%s", res.Readme)
+		}
+	})
+
+	t.Run("not a plain description", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Comments.PlainAsDescription = true
+		res := mustGenerate(t, "## @param a A\n## @example\n## Use a small value\n## @exampleEnd\na: 0\n", cfg)
+		if got := tableCells(tableRow(t, res.Readme, "a"))[1]; got != "A" {
+			t.Errorf("description %q, want A", got)
+		}
+	})
+}
@@ -0,0 +1,1211 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestDeduplicateObjects(t *testing.T) {
+	const sameKey = `## @param a.limits.cpu C
+## @param a.name N
+## @param b.limits.cpu C
+a:
+  limits:
+    cpu: 1
+  name: a
+b:
+  limits:
+    cpu: 1
+`
+	const otherKeys = `## @param r1.cpu C
+## @param r2.cpu C
+r1:
+  cpu: 1
+r2:
+  cpu: 1
+`
+	tests := []struct {
+		name        string
+		values      string
+		dialect     string
+		deduplicate bool
+		defsKey     string
+		refs        map[string]string
+	}{
+		{
+			name:        "same key",
+			values:      sameKey,
+			dialect:     schemaDialectDraft07,
+			deduplicate: true,
+			defsKey:     "definitions",
+			refs:        map[string]string{"a.limits": "#/definitions/limits", "b.limits": "#/definitions/limits"},
+		},
+		{
+			name:        "different keys",
+			values:      otherKeys,
+			dialect:     schemaDialectDraft07,
+			deduplicate: true,
+			defsKey:     "definitions",
+			refs:        map[string]string{"r1": "#/definitions/object-", "r2": "#/definitions/object-"},
+		},
+		{
+			name:        "openapi",
+			values:      sameKey,
+			dialect:     schemaDialectOpenAPI,
+			deduplicate: true,
+			defsKey:     "x-definitions",
+			refs:        map[string]string{"a.limits": "#/x-definitions/limits", "b.limits": "#/x-definitions/limits"},
+		},
+		{
+			name:    "openapi disabled",
+			values:  sameKey,
+			dialect: schemaDialectOpenAPI,
+		},
+		{
+			name:    "disabled",
+			values:  sameKey,
+			dialect: schemaDialectDraft07,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Schema.Dialect = tt.dialect
+			cfg.Schema.Deduplicate = tt.deduplicate
+			schema := mustGenerate(t, tt.values, cfg).Schema
+			var root map[string]interface{}
+			if err := json.Unmarshal(schema, &root); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range []string{"definitions", "x-definitions"} {
+				if _, ok := root[key]; ok != (key == tt.defsKey) {
+					t.Fatalf("%s present = %v, want %v:\n%s", key, ok, key == tt.defsKey, schema)
+				}
+			}
+			// The references resolve, as --lint-values follows them.
+			inlineLocalRefs(root)
+			if errs := validateValue("", map[string]interface{}{"a": map[string]interface{}{"limits": map[string]interface{}{"cpu": "x"}}},
+				root); tt.values == sameKey && len(errs) == 0 {
+				t.Errorf("a.limits.cpu: string accepted after inlining:\n%s", schema)
+			}
+			var first string
+			for path, want := range tt.refs {
+				ref, _ := property(t, schema, path)["$ref"].(string)
+				if !strings.HasPrefix(ref, want) {
+					t.Errorf("%s: $ref = %q, want %s…", path, ref, want)
+				}
+				if first != "" && ref != first {
+					t.Errorf("%s: $ref = %q, want the shared %q", path, ref, first)
+				}
+				first = ref
+			}
+		})
+	}
+}
+
+func TestDurationAndByteSizePatterns(t *testing.T) {
+	tests := []struct {
+		modifier string
+		value    string
+		match    bool
+	}{
+		{"duration", "30s", true},
+		{"duration", "1h30m", true},
+		{"duration", "1.5h", true},
+		{"duration", "250ms", true},
+		{"duration", "30", false},
+		{"duration", "30 seconds", false},
+		{"bytesize", "1Gi", true},
+		{"bytesize", "512M", true},
+		{"bytesize", "100", true},
+		{"bytesize", "1.5Ti", true},
+		{"bytesize", "1GB", false},
+		{"bytesize", "Gi", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.modifier+" "+tt.value, func(t *testing.T) {
+			values := "## @param v [" + tt.modifier + "] V\nv: \"" + tt.value + "\"\n"
+			prop := property(t, mustGenerate(t, values, nil).Schema, "v")
+			pattern, _ := prop["pattern"].(string)
+			if pattern == "" {
+				t.Fatalf("no pattern in %v", prop)
+			}
+			if got := regexp.MustCompile(pattern).MatchString(tt.value); got != tt.match {
+				t.Errorf("%s matches %q = %v, want %v", pattern, tt.value, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestPropertyNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  string
+		pattern interface{}
+		err     string
+	}{
+		{
+			name:    "pattern",
+			values:  "## @param labels [object,propertyNames:^[a-z]{1,63}$] Labels\nlabels: {}\n",
+			pattern: "^[a-z]{1,63}$",
+		},
+		{
+			name:   "none",
+			values: "## @param labels [object] Labels\nlabels: {}\n",
+		},
+		{
+			name:   "invalid pattern",
+			values: "## @param labels [object,propertyNames:*a] Labels\nlabels: {}\n",
+			err:    "labels: invalid propertyNames pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err != "" {
+				_, err := Generate(Options{Values: []byte(tt.values), Schema: true})
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			prop := property(t, mustGenerate(t, tt.values, nil).Schema, "labels")
+			names, _ := prop["propertyNames"].(map[string]interface{})
+			if names["pattern"] != tt.pattern {
+				t.Errorf("propertyNames = %v, want pattern %v", prop["propertyNames"], tt.pattern)
+			}
+		})
+	}
+}
+
+func TestSchemaID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want interface{}
+	}{
+		{id: "", want: nil},
+		{id: "https://charts.example.com/app/values.schema.json", want: "https://charts.example.com/app/values.schema.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Schema.ID = tt.id
+			var root map[string]interface{}
+			if err := json.Unmarshal(mustGenerate(t, "## @param a A\na: 1\n", cfg).Schema, &root); err != nil {
+				t.Fatal(err)
+			}
+			if root["$id"] != tt.want {
+				t.Errorf("$id = %v, want %v", root["$id"], tt.want)
+			}
+		})
+	}
+}
+
+func TestNestedArrayIndexes(t *testing.T) {
+	const values = `## @param matrix[0][0] Cell
+matrix: [[1]]
+## @param servers[0].host Host
+## @param servers[0].ports[0] Port
+servers:
+  - host: a
+    ports: [80]
+`
+	tests := []struct {
+		path string
+		typ  string
+	}{
+		{"matrix", "array"},
+		{"matrix[0]", "array"},
+		{"matrix[0][0]", "integer"},
+		{"servers", "array"},
+		{"servers[0]", "object"},
+		{"servers[0].host", "string"},
+		{"servers[0].ports", "array"},
+		{"servers[0].ports[0]", "integer"},
+	}
+	schema := mustGenerate(t, values, nil).Schema
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := property(t, schema, tt.path)["type"]; got != tt.typ {
+				t.Errorf("type = %v, want %s", got, tt.typ)
+			}
+		})
+	}
+}
+
+// violations reports the violations of the YAML document values against
+// schema, using the --lint-values validator.
+func violations(t *testing.T, schema []byte, values string) []string {
+	t.Helper()
+	var s map[string]interface{}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(values), &v); err != nil {
+		t.Fatal(err)
+	}
+	return validateValue("", v, s)
+}
+
+func TestOneOfGroup(t *testing.T) {
+	const values = `## @param storage.s3 [object,nullable,oneOf-group:backend] S3
+## @param storage.gcs [object,nullable,oneOf-group:backend] GCS
+storage:
+  s3:
+  gcs:
+`
+	res := mustGenerate(t, values, nil)
+	tests := []struct {
+		name   string
+		values string
+		valid  bool
+	}{
+		{name: "none", values: "storage: {s3: null, gcs: null}", valid: true},
+		{name: "one", values: "storage: {s3: {bucket: b}, gcs: null}", valid: true},
+		{name: "both", values: "storage: {s3: {bucket: b}, gcs: {bucket: c}}", valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := violations(t, res.Schema, tt.values); (len(got) == 0) != tt.valid {
+				t.Errorf("violations %v, want valid = %v", got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestOneOfGroupWarnsWhenValuesSetSeveral(t *testing.T) {
+	tests := []struct {
+		values   string
+		warnings int
+	}{
+		{values: "storage:\n  s3: {bucket: b}\n  gcs:\n", warnings: 0},
+		{values: "storage:\n  s3: {bucket: b}\n  gcs: {bucket: c}\n", warnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.warnings), func(t *testing.T) {
+			values := "## @param storage.s3 [object,nullable,oneOf-group:backend] S3\n" +
+				"## @param storage.gcs [object,nullable,oneOf-group:backend] GCS\n" + tt.values
+			res, err := Generate(Options{Values: []byte(values), Schema: true})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if res.Warnings != tt.warnings {
+				t.Errorf("Warnings = %d, want %d", res.Warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestEmptySchemaWarning(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   string
+		warnings int
+	}{
+		{name: "all skipped", values: "## @skip a\na:\n  b: 1\n## @skip c\nc: 2\n", warnings: 1},
+		{name: "skipped and extra", values: "## @extra other Not in values\n## @skip a\na:\n  b: 1\n", warnings: 1},
+		{name: "one documented", values: "## @skip a\na:\n  b: 1\n## @param c C\nc: 2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log strings.Builder
+			res, err := Generate(Options{Values: []byte(tt.values), Schema: true, Log: &log})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if res.Warnings != tt.warnings {
+				t.Errorf("Warnings = %d, want %d; log %q", res.Warnings, tt.warnings, log.String())
+			}
+			if tt.warnings > 0 && !strings.Contains(log.String(), "generated schema has no properties") {
+				t.Errorf("log %q does not report the empty schema", log.String())
+			}
+		})
+	}
+}
+
+func TestIfRequired(t *testing.T) {
+	const siblings = `## @param tls.enabled Enable TLS
+## @param tls.cert [if-required:tls.enabled] Certificate
+tls:
+  enabled: false
+  cert: ""
+`
+	const branches = `## @param tls.enabled Enable TLS
+## @param ingress.secret [if-required:tls.enabled] Secret
+tls:
+  enabled: false
+ingress:
+  secret: ""
+`
+	tests := []struct {
+		name   string
+		schema string
+		values string
+		valid  bool
+	}{
+		{name: "flag off", schema: siblings, values: "tls: {enabled: false}", valid: true},
+		{name: "flag on, set", schema: siblings, values: "tls: {enabled: true, cert: c}", valid: true},
+		{name: "flag on, missing", schema: siblings, values: "tls: {enabled: true}", valid: false},
+		{name: "other branch, flag off", schema: branches, values: "{tls: {enabled: false}, ingress: {}}", valid: true},
+		{name: "other branch, set", schema: branches, values: "{tls: {enabled: true}, ingress: {secret: s}}", valid: true},
+		{name: "other branch, missing", schema: branches, values: "{tls: {enabled: true}, ingress: {}}", valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := mustGenerate(t, tt.schema, nil)
+			if got := violations(t, res.Schema, tt.values); (len(got) == 0) != tt.valid {
+				t.Errorf("violations %v, want valid = %v", got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestIfRequiredBlock(t *testing.T) {
+	const values = `## @param tls.enabled Enable TLS
+## @param tls.cert [if-required:tls.enabled] Certificate
+tls:
+  enabled: false
+  cert: ""
+`
+	want := `[{"if":{"properties":{"enabled":{"const":true}},"required":["enabled"]},"then":{"required":["cert"]}}]`
+	got, _ := json.Marshal(property(t, mustGenerate(t, values, nil).Schema, "tls")["allOf"])
+	if string(got) != want {
+		t.Errorf("allOf = %s, want %s", got, want)
+	}
+}
+
+func TestIfRequiredUndocumentedFlag(t *testing.T) {
+	const values = "## @section S\n## @param tls.cert [if-required:tls.enabled] Certificate\ntls:\n  cert: \"\"\n"
+	_, err := Generate(Options{Values: []byte(values), Schema: true})
+	if want := "tls.cert: if-required references undocumented key tls.enabled"; err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+}
+
+func TestNormalizeDescriptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		desc       string
+		capitalize bool
+		period     string
+		want       string
+	}{
+		{name: "keep", desc: "number of replicas.", period: trailingPeriodKeep, want: "number of replicas."},
+		{name: "capitalize", desc: "number of replicas", capitalize: true, period: trailingPeriodKeep, want: "Number of replicas"},
+		{name: "capitalize non-ASCII", desc: "élan", capitalize: true, period: trailingPeriodKeep, want: "Élan"},
+		{name: "strip", desc: "Number of replicas.", period: trailingPeriodStrip, want: "Number of replicas"},
+		{name: "add", desc: "number of replicas", capitalize: true, period: trailingPeriodAdd, want: "Number of replicas."},
+		{name: "add once", desc: "Number of replicas.", period: trailingPeriodAdd, want: "Number of replicas."},
+		{name: "empty", desc: "", capitalize: true, period: trailingPeriodAdd, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Schema.CapitalizeDescriptions = tt.capitalize
+			cfg.Schema.TrailingPeriod = tt.period
+			res := mustGenerate(t, "## @param replicas "+tt.desc+"\nreplicas: 1\n", cfg)
+			if got := property(t, res.Schema, "replicas")["description"]; got != tt.want {
+				t.Errorf("description %q, want %q", got, tt.want)
+			}
+			if got := tableCells(tableRow(t, res.Readme, "replicas"))[1]; got != tt.desc {
+				t.Errorf("README description %q, want it unchanged: %q", got, tt.desc)
+			}
+		})
+	}
+}
+
+func TestTypeList(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		types   string
+		value   string
+		want    string
+	}{
+		{name: "openapi", dialect: schemaDialectOpenAPI, types: "string|integer", value: "80",
+			want: `{"default":80,"description":"P","oneOf":[{"type":"string"},{"type":"integer"}]}`},
+		{name: "draft-07", dialect: schemaDialectDraft07, types: "string|integer", value: "80",
+			want: `{"default":80,"description":"P","type":["string","integer"]}`},
+		{name: "openapi nullable", dialect: schemaDialectOpenAPI, types: "string|null", value: "",
+			want: `{"default":null,"description":"P","nullable":true,"type":"string"}`},
+		{name: "openapi null alternative", dialect: schemaDialectOpenAPI, types: "string|integer|null", value: "",
+			want: `{"default":null,"description":"P","oneOf":[{"type":"string"},{"type":"integer"},{"enum":[null]}]}`},
+		{name: "draft-07 null", dialect: schemaDialectDraft07, types: "string|null", value: "",
+			want: `{"default":null,"description":"P","type":["string","null"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Schema.Dialect = tt.dialect
+			res := mustGenerate(t, "## @param p [type:"+tt.types+"] P\np: "+tt.value+"\n", cfg)
+			got, _ := json.Marshal(property(t, res.Schema, "p"))
+			if string(got) != tt.want {
+				t.Errorf("p = %s, want %s", got, tt.want)
+			}
+			for _, typ := range []string{"integer", "string", "null"} {
+				v := map[string]string{"integer": "p: 80", "string": "p: \"80\"", "null": "p: null"}[typ]
+				want := slices.Contains(strings.Split(tt.types, "|"), typ)
+				if got := violations(t, res.Schema, v); (len(got) == 0) != want {
+					t.Errorf("%s: violations %v, want valid = %v", v, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTypeListUnknownType(t *testing.T) {
+	_, err := Generate(Options{Values: []byte("## @section S\n## @param p [type:string|nul] P\np: a\n"), Schema: true})
+	if want := `p: unknown type "nul" in modifier "type:string|nul"`; err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+}
+
+func TestPermissiveObject(t *testing.T) {
+	const values = "## @param podAnnotations [object] Annotations\npodAnnotations: {}\n## @param labels [object] Labels\nlabels:\n  app: web\n"
+	tests := []struct {
+		name   string
+		closed bool
+		want   string
+	}{
+		{name: "open schema", want: `{"default":{},"description":"Annotations","type":"object"}`},
+		{name: "closed schema", closed: true, want: `{"additionalProperties":true,"default":{},"description":"Annotations","type":"object"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Schema.AdditionalProperties = !tt.closed
+			res := mustGenerate(t, values, cfg)
+			if got, _ := json.Marshal(property(t, res.Schema, "podAnnotations")); string(got) != tt.want {
+				t.Errorf("podAnnotations = %s, want %s", got, tt.want)
+			}
+			if got := violations(t, res.Schema, "podAnnotations: {any: key}\nlabels: {app: web, tier: db}"); len(got) > 0 {
+				t.Errorf("violations %v, want none", got)
+			}
+			if got := violations(t, res.Schema, "podAnnotations: [a]"); len(got) == 0 {
+				t.Error("an array validates as podAnnotations")
+			}
+		})
+	}
+}
+
+func TestSchemaSections(t *testing.T) {
+	const values = `## @param top Outside any section
+top: 1
+## @section Web
+## @param web.port Port
+## @param web.extra [object] Extra
+web:
+  port: 80
+  extra: {}
+## @section # Database
+## @param db.size Size
+db:
+  size: 1
+`
+	tests := []struct {
+		enabled  bool
+		sections map[string]interface{}
+	}{
+		{enabled: false, sections: map[string]interface{}{"top": nil, "web.port": nil, "web.extra": nil, "db.size": nil}},
+		{enabled: true, sections: map[string]interface{}{"top": nil, "web.port": "Web", "web.extra": "Web", "db.size": "Database"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.enabled), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Schema.Sections = tt.enabled
+			res, err := Generate(Options{Values: []byte(values), Schema: true, Config: cfg})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			for key, want := range tt.sections {
+				if got := property(t, res.Schema, key)["x-section"]; got != want {
+					t.Errorf("%s: x-section = %v, want %v", key, got, want)
+				}
+			}
+			if got := property(t, res.Schema, "web")["x-section"]; got != nil {
+				t.Errorf("intermediate object web has x-section %v", got)
+			}
+		})
+	}
+}
+
+func TestPlaceholderKeys(t *testing.T) {
+	const doc = "## @param extraVolumes.<name>.mountPath Mount path\n## @param extraVolumes.<name>.readOnly Read only\n"
+	tests := []struct {
+		name   string
+		values string
+		types  map[string]interface{}
+		log    string
+	}{
+		{
+			name:   "entries",
+			values: doc + "extraVolumes:\n  data:\n    mountPath: /data\n    readOnly: true\n  logs:\n    mountPath: /logs\n",
+			types:  map[string]interface{}{"mountPath": "string", "readOnly": "boolean"},
+		},
+		{
+			name:   "empty map",
+			values: doc + "extraVolumes: {}\n",
+			types:  map[string]interface{}{"mountPath": nil, "readOnly": nil},
+		},
+		{
+			name:   "placeholder with a modifier",
+			values: "## @param extraVolumes.<name> [object] Volume\nextraVolumes: {}\n",
+		},
+		{
+			name:   "undocumented entry key",
+			values: doc + "extraVolumes:\n  data:\n    mountPath: /data\n    size: 1Gi\n",
+			log:    "Missing metadata for key: extraVolumes.data.size",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log strings.Builder
+			res, err := Generate(Options{Values: []byte("## @section S\n" + tt.values), Schema: true, Log: &log})
+			if tt.log != "" {
+				if err == nil || !strings.Contains(log.String(), tt.log) {
+					t.Fatalf("error = %v, log %q; want %q", err, log.String(), tt.log)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v; log %q", err, log.String())
+			}
+			entry, _ := property(t, res.Schema, "extraVolumes")["additionalProperties"].(map[string]interface{})
+			props, _ := entry["properties"].(map[string]interface{})
+			for key, want := range tt.types {
+				prop, ok := props[key].(map[string]interface{})
+				if !ok {
+					t.Fatalf("entries have no property %s:\n%s", key, res.Schema)
+				}
+				if prop["type"] != want {
+					t.Errorf("%s: type %v, want %v", key, prop["type"], want)
+				}
+			}
+			if got := violations(t, res.Schema, "extraVolumes: {cache: {mountPath: /cache}}"); len(got) > 0 {
+				t.Errorf("violations %v, want none", got)
+			}
+		})
+	}
+}
+
+func TestArrayElementItems(t *testing.T) {
+	const values = `## @param containers[0].name Name of the first
+## @param containers[0].port Port
+## @param containers[1].name Second name
+## @param containers[1].port Second port
+## @param containers[1].image Image, only set on the second
+containers:
+  - name: web
+    port: 80
+  - name: sidecar
+    port: 90
+    image: busybox
+`
+	res := mustGenerate(t, values, nil)
+	if typ := property(t, res.Schema, "containers")["type"]; typ != "array" {
+		t.Errorf("containers: type %v, want array", typ)
+	}
+	tests := []struct {
+		key  string
+		typ  string
+		desc string
+		dflt interface{}
+	}{
+		{key: "containers[0].name", typ: "string", desc: "Name of the first", dflt: "web"},
+		{key: "containers[0].port", typ: "integer", desc: "Port", dflt: 80},
+		{key: "containers[0].image", typ: "string", desc: "Image, only set on the second", dflt: "busybox"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			prop := property(t, res.Schema, tt.key)
+			if prop["type"] != tt.typ || prop["description"] != tt.desc || !jsonEqual(prop["default"], tt.dflt) {
+				t.Errorf("type %v, description %v, default %v; want %s, %s, %v", prop["type"], prop["description"], prop["default"], tt.typ, tt.desc, tt.dflt)
+			}
+		})
+	}
+	if got := violations(t, res.Schema, values); len(got) > 0 {
+		t.Errorf("the values do not validate: %v", got)
+	}
+	if got := violations(t, res.Schema, "containers: [{name: web, port: web}]"); len(got) != 1 {
+		t.Errorf("violations %v, want one for the port", got)
+	}
+	if strings.Contains(string(res.Schema), "containers[") {
+		t.Errorf("schema has an indexed property name:\n%s", res.Schema)
+	}
+}
+
+func TestRootType(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   string
+		rootType string
+		want     string
+		warnings int
+	}{
+		{name: "map root", values: "## @param a A\na: 1\n", want: "object"},
+		{name: "list root", values: "## @param [0].name Name\n- name: x\n", want: "array"},
+		{name: "scalar root", values: "42\n", want: "integer"},
+		{name: "scalar list root", values: "## @param [0] First\n- 1\n", want: "array"},
+		{name: "forced array", values: "## @param [0].name Name\n- name: x\n", rootType: "array", want: "array"},
+		{name: "index under object", values: "## @param [0].name Name\n- name: x\n", rootType: "object", want: "object", warnings: 2},
+		{name: "name under array", values: "## @param a A\na: 1\n", rootType: "array", want: "array", warnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Schema.RootType = tt.rootType
+			var log strings.Builder
+			res, err := Generate(Options{Values: []byte(tt.values), Schema: true, Config: cfg, Log: &log})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			var root map[string]interface{}
+			if err := json.Unmarshal(res.Schema, &root); err != nil {
+				t.Fatal(err)
+			}
+			if root["type"] != tt.want {
+				t.Errorf("root type %v, want %s:\n%s", root["type"], tt.want, res.Schema)
+			}
+			if res.Warnings != tt.warnings {
+				t.Errorf("Warnings = %d, want %d; log %q", res.Warnings, tt.warnings, log.String())
+			}
+			if tt.rootType != "" && tt.warnings > 0 && !strings.Contains(log.String(), "does not fit the "+tt.rootType+" schema root") {
+				t.Errorf("log %q does not report the skipped parameter", log.String())
+			}
+		})
+	}
+
+	t.Run("list element", func(t *testing.T) {
+		res, err := Generate(Options{Values: []byte("## @param [0].name Name\n- name: x\n"), Schema: true})
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		if prop := property(t, res.Schema, "[0].name"); prop["type"] != "string" || prop["default"] != "x" {
+			t.Errorf("[0].name = %v, want a string defaulting to x", prop)
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Schema.RootType = "map"
+		if _, err := Generate(Options{Values: []byte("a: 1\n"), Schema: true, Config: cfg}); err == nil || !strings.Contains(err.Error(), `invalid schema.rootType "map"`) {
+			t.Errorf("error %v, want invalid schema.rootType", err)
+		}
+	})
+}
+
+func TestNumberTypes(t *testing.T) {
+	tests := []struct {
+		value string
+		typ   string
+		shown string
+	}{
+		{value: "0", typ: "integer", shown: "`0`"},
+		{value: "-5", typ: "integer", shown: "`-5`"},
+		{value: "1000000000000", typ: "integer", shown: "`1000000000000`"},
+		{value: "3.14", typ: "number", shown: "`3.14`"},
+		{value: "-0.5", typ: "number", shown: "`-0.5`"},
+		{value: "1e3", typ: "number", shown: "`1000`"},
+		{value: "1.0", typ: "number", shown: "`1`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			res := mustGenerate(t, "## @param n N\nn: "+tt.value+"\n", nil)
+			if typ := property(t, res.Schema, "n")["type"]; typ != tt.typ {
+				t.Errorf("type %v, want %s", typ, tt.typ)
+			}
+			if got := tableCells(tableRow(t, res.Readme, "n"))[2]; got != tt.shown {
+				t.Errorf("value %s, want %s", got, tt.shown)
+			}
+			wantViolations := 0
+			if tt.typ == "integer" {
+				wantViolations = 1
+			}
+			if got := violations(t, res.Schema, "n: 2.5"); len(got) != wantViolations {
+				t.Errorf("violations for 2.5: %v, want %d", got, wantViolations)
+			}
+		})
+	}
+}
+
+func TestK8sType(t *testing.T) {
+	const def = "io.k8s.api.core.v1.SecurityContext"
+	tests := []struct {
+		name        string
+		modifiers   string
+		definitions string
+		ref         string
+		dflt        interface{}
+	}{
+		{name: "default definitions", modifiers: "k8s-type:" + def,
+			ref:  "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/master/_definitions.json#/definitions/" + def,
+			dflt: map[string]interface{}{"runAsUser": 1001}},
+		{name: "local definitions", modifiers: "k8s-type:" + def, definitions: "file:///defs.json",
+			ref: "file:///defs.json#/definitions/" + def, dflt: map[string]interface{}{"runAsUser": 1001}},
+		{name: "object default", modifiers: "object, k8s-type:" + def, definitions: "defs.json",
+			ref: "defs.json#/definitions/" + def, dflt: map[string]interface{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			if tt.definitions != "" {
+				cfg.Schema.KubernetesDefinitions = tt.definitions
+			}
+			res := mustGenerate(t, "## @param securityContext ["+tt.modifiers+"] Pod security context\nsecurityContext:\n  runAsUser: 1001\n", cfg)
+			prop := property(t, res.Schema, "securityContext")
+			if prop["$ref"] != tt.ref || prop["description"] != "Pod security context" {
+				t.Errorf("$ref %v, description %v; want %s", prop["$ref"], prop["description"], tt.ref)
+			}
+			if typ, ok := prop["type"]; ok {
+				t.Errorf("type %v next to the $ref", typ)
+			}
+			if !jsonEqual(prop["default"], tt.dflt) {
+				t.Errorf("default %v, want %v", prop["default"], tt.dflt)
+			}
+		})
+	}
+}
+
+func TestInlineLocalRefs(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{
+			name:   "definition",
+			schema: `{"properties": {"a": {"$ref": "#/definitions/limits"}}, "definitions": {"limits": {"type": "object"}}}`,
+			want:   `{"properties": {"a": {"type": "object"}}, "definitions": {"limits": {"type": "object"}}}`,
+		},
+		{
+			name:   "escaped name",
+			schema: `{"properties": {"a": {"$ref": "#/definitions/a~1b"}}, "definitions": {"a/b": {"type": "string"}}}`,
+			want:   `{"properties": {"a": {"type": "string"}}, "definitions": {"a/b": {"type": "string"}}}`,
+		},
+		{
+			name:   "other document",
+			schema: `{"properties": {"a": {"$ref": "defs.json#/definitions/x"}}}`,
+			want:   `{"properties": {"a": {"$ref": "defs.json#/definitions/x"}}}`,
+		},
+		{
+			name:   "unresolvable",
+			schema: `{"properties": {"a": {"$ref": "#/definitions/missing"}}}`,
+			want:   `{"properties": {"a": {"$ref": "#/definitions/missing"}}}`,
+		},
+		{
+			name:   "cycle",
+			schema: `{"properties": {"a": {"$ref": "#/definitions/node"}}, "definitions": {"node": {"items": {"$ref": "#/definitions/node"}}}}`,
+			want: `{"properties": {"a": {"items": {"$ref": "#/definitions/node"}}},
+				"definitions": {"node": {"items": {"items": {"$ref": "#/definitions/node"}}}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var root, want map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.schema), &root); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			inlineLocalRefs(root)
+			if !jsonEqual(root, want) {
+				got, _ := json.Marshal(root)
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternModifier(t *testing.T) {
+	tests := []struct {
+		name      string
+		modifiers string
+		pattern   interface{}
+		message   interface{}
+		valid     string
+		invalid   string
+		err       string
+	}{
+		{name: "pattern", modifiers: "pattern:^[a-z]+$", pattern: "^[a-z]+$", valid: "abc", invalid: "ABC"},
+		{name: "with message", modifiers: "pattern:^[a-z]+$,errorMessage:must be lowercase letters (a to z)",
+			pattern: "^[a-z]+$", message: "must be lowercase letters (a to z)", valid: "abc", invalid: "ABC"},
+		{name: "comma in a repeat", modifiers: "pattern:^[a-c]{1,3}$", pattern: "^[a-c]{1,3}$", valid: "abc", invalid: "abca"},
+		{name: "escape", modifiers: `pattern:^\d+$`, pattern: `^\d+$`, valid: `"12"`, invalid: "1a"},
+		{name: "message only", modifiers: "errorMessage:oops", message: "oops"},
+		{name: "invalid", modifiers: "pattern:*a", err: "name: invalid pattern pattern: error parsing regexp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := "## @section S\n## @param name [" + tt.modifiers + "] Release name\nname: abc\n"
+			res, err := Generate(Options{Values: []byte(values), Schema: true})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			prop := property(t, res.Schema, "name")
+			if prop["pattern"] != tt.pattern || prop["errorMessage"] != tt.message || prop["description"] != "Release name" {
+				t.Errorf("pattern %v, errorMessage %v, description %v; want %v, %v, Release name",
+					prop["pattern"], prop["errorMessage"], prop["description"], tt.pattern, tt.message)
+			}
+			if tt.valid == "" {
+				return
+			}
+			if got := violations(t, res.Schema, "name: "+tt.valid); len(got) > 0 {
+				t.Errorf("violations for %q: %v, want none", tt.valid, got)
+			}
+			if got := violations(t, res.Schema, "name: "+tt.invalid); len(got) != 1 {
+				t.Errorf("violations for %q: %v, want one", tt.invalid, got)
+			}
+		})
+	}
+}
+
+func TestEnum(t *testing.T) {
+	tests := []struct {
+		name      string
+		modifiers string
+		value     string
+		enum      []interface{}
+		invalid   string
+		err       string
+	}{
+		{name: "strings", modifiers: "enum:ClusterIP,NodePort,LoadBalancer", value: "ClusterIP",
+			enum: []interface{}{"ClusterIP", "NodePort", "LoadBalancer"}, invalid: "Ingress"},
+		{name: "spaces trimmed", modifiers: "enum: a , b ", value: "a", enum: []interface{}{"a", "b"}, invalid: "c"},
+		{name: "integers", modifiers: "enum:1,2,3", value: "1", enum: []interface{}{1, 2, 3}, invalid: `"1"`},
+		{name: "numbers", modifiers: "enum:1.5,2", value: "1.5", enum: []interface{}{1.5, 2}, invalid: "3"},
+		{name: "booleans", modifiers: "enum:true,false", value: "true", enum: []interface{}{true, false}, invalid: `"true"`},
+		{name: "followed by a modifier", modifiers: "enum:a,b,nullable", value: "a", enum: []interface{}{"a", "b", nil}, invalid: "c"},
+		{name: "empty entry", modifiers: "enum:a,,b", value: "a", err: `n: enum "a,,b" has an empty entry`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := "## @section S\n## @param n [" + tt.modifiers + "] N\nn: " + tt.value + "\n"
+			res, err := Generate(Options{Values: []byte(values), Schema: true})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if enum := property(t, res.Schema, "n")["enum"]; !jsonEqual(enum, tt.enum) {
+				t.Errorf("enum %v, want %v", enum, tt.enum)
+			}
+			if got := violations(t, res.Schema, "n: "+tt.value); len(got) > 0 {
+				t.Errorf("the default does not validate: %v", got)
+			}
+			if got := violations(t, res.Schema, "n: "+tt.invalid); len(got) == 0 {
+				t.Errorf("%s validates", tt.invalid)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name      string
+		modifiers string
+		redact    interface{}
+		shown     string
+	}{
+		{name: "redacted", modifiers: "[redact] ", redact: true, shown: "`s3cr3t`"},
+		{name: "with a type", modifiers: "[string, redact] ", redact: true, shown: "`\"\"`"},
+		{name: "plain", shown: "`s3cr3t`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := mustGenerate(t, "## @param password "+tt.modifiers+"Password\npassword: s3cr3t\n", nil)
+			if got := property(t, res.Schema, "password")["x-redact"]; got != tt.redact {
+				t.Errorf("x-redact %v, want %v", got, tt.redact)
+			}
+			if got := tableCells(tableRow(t, res.Readme, "password"))[2]; got != tt.shown {
+				t.Errorf("value %s, want %s", got, tt.shown)
+			}
+		})
+	}
+}
+
+func TestTupleItems(t *testing.T) {
+	const values = "## @param replicas[0] Minimum\n## @param replicas[1] Maximum\nreplicas: [1, 5]\n" +
+		"## @param pair[0] Name\n## @param pair[1] Count\npair: [a, 1]\n## @param one[0] Only\none: [a]\n"
+	tests := []struct {
+		dialect string
+		key     string
+		items   string
+		xItems  string
+	}{
+		{dialect: schemaDialectDraft07, key: "replicas",
+			items: `[{"default":1,"description":"Minimum","type":"integer"},{"default":5,"description":"Maximum","type":"integer"}]`},
+		{dialect: schemaDialectDraft07, key: "pair",
+			items: `[{"default":"a","description":"Name","type":"string"},{"default":1,"description":"Count","type":"integer"}]`},
+		{dialect: schemaDialectDraft07, key: "one", items: `{"default":"a","description":"Only","type":"string"}`},
+		{dialect: schemaDialectOpenAPI, key: "replicas", items: `{"type":"integer"}`,
+			xItems: `[{"default":1,"description":"Minimum","type":"integer"},{"default":5,"description":"Maximum","type":"integer"}]`},
+		{dialect: schemaDialectOpenAPI, key: "pair", items: `{}`,
+			xItems: `[{"default":"a","description":"Name","type":"string"},{"default":1,"description":"Count","type":"integer"}]`},
+		{dialect: schemaDialectOpenAPI, key: "one", items: `{"default":"a","description":"Only","type":"string"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect+"/"+tt.key, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Schema.Dialect = tt.dialect
+			res := mustGenerate(t, values, cfg)
+			prop := property(t, res.Schema, tt.key)
+			var items, xItems interface{}
+			json.Unmarshal([]byte(tt.items), &items)
+			if tt.xItems != "" {
+				json.Unmarshal([]byte(tt.xItems), &xItems)
+			}
+			if !jsonEqual(prop["items"], items) || !jsonEqual(prop["x-items"], xItems) {
+				t.Errorf("items %v, x-items %v; want %s, %s", prop["items"], prop["x-items"], tt.items, tt.xItems)
+			}
+			if got := violations(t, res.Schema, values); len(got) > 0 {
+				t.Errorf("the values do not validate: %v", got)
+			}
+		})
+	}
+
+	cfg := DefaultConfig()
+	cfg.Schema.Dialect = schemaDialectDraft07
+	res := mustGenerate(t, values, cfg)
+	for value, want := range map[string]int{"pair: [1, a]": 2, "pair: [a, 1, extra]": 0, "pair: [a]": 0} {
+		if got := violations(t, res.Schema, value); len(got) != want {
+			t.Errorf("violations for %q: %v, want %d", value, got, want)
+		}
+	}
+}
+
+func TestClosedObjects(t *testing.T) {
+	const values = "## @param replicas Replicas\nreplicas: 1\n## @param image.tag Tag\nimage:\n  tag: x\n## @param extra [object] Extra\nextra: {}\n" +
+		"## @param labels.<name> [string] Label\nlabels: {}\n## @param list[0].name Name\nlist:\n  - name: a\n"
+	tests := []struct {
+		name       string
+		additional bool
+		value      string
+		violations []string
+	}{
+		{name: "misspelt root key", value: "replicaCount: 1", violations: []string{"replicaCount: key is not allowed by the schema"}},
+		{name: "unknown nested key", value: "image: {tag: x, digest: y}", violations: []string{"image.digest: key is not allowed by the schema"}},
+		{name: "unknown element key", value: "list: [{name: a, other: 1}]", violations: []string{"list[0].other: key is not allowed by the schema"}},
+		{name: "undocumented object", value: "extra: {any: 1}"},
+		{name: "placeholder entry", value: "labels: {a: b}"},
+		{name: "placeholder entry type", value: "labels: {a: 1}", violations: []string{"labels.a: expected string, got integer"}},
+		{name: "open", additional: true, value: "replicaCount: 1\nimage: {tag: x, digest: y}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Schema.AdditionalProperties = tt.additional
+			res := mustGenerate(t, values, cfg)
+			if got := violations(t, res.Schema, values); len(got) > 0 {
+				t.Errorf("the defaults do not validate: %v", got)
+			}
+			if got := violations(t, res.Schema, tt.value); strings.Join(got, "\n") != strings.Join(tt.violations, "\n") {
+				t.Errorf("violations %q, want %q", got, tt.violations)
+			}
+		})
+	}
+}
+
+func TestTupleObjects(t *testing.T) {
+	const values = "## @param pair[0] [object] Settings\n## @param pair[1] Count\npair:\n  - {}\n  - 1\n"
+	tests := []struct {
+		dialect string
+		list    string
+	}{
+		{dialect: schemaDialectDraft07, list: "items"},
+		{dialect: schemaDialectOpenAPI, list: "x-items"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Schema.Dialect = tt.dialect
+			cfg.Schema.AdditionalProperties = false
+			res := mustGenerate(t, values, cfg)
+			list, _ := property(t, res.Schema, "pair")[tt.list].([]interface{})
+			if len(list) != 2 {
+				t.Fatalf("%s: %v, want two positions", tt.list, list)
+			}
+			if got := list[0].(map[string]interface{})["additionalProperties"]; got != true {
+				t.Errorf("object position: additionalProperties %v, want true", got)
+			}
+			if got := violations(t, res.Schema, "pair: [{any: 1}, 1]"); len(got) > 0 {
+				t.Errorf("violations %q for an undocumented object", got)
+			}
+		})
+	}
+}
+
+func TestDNSNames(t *testing.T) {
+	res := mustGenerate(t, "## @param label [dns-label] Namespace\nlabel: default\n## @param sub [dns-subdomain] Secret\nsub: my.secret\n",
+		DefaultConfig())
+	for key, max := range map[string]float64{"label": 63, "sub": 253} {
+		if got := property(t, res.Schema, key)["maxLength"]; got != max {
+			t.Errorf("%s: maxLength %v, want %v", key, got, max)
+		}
+	}
+	if got := property(t, res.Schema, "label")["pattern"]; got != DefaultConfig().Patterns.DNSLabel {
+		t.Errorf("label: pattern %v", got)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		label bool
+		sub   bool
+	}{
+		{name: "lowercase", value: "my-app", label: true, sub: true},
+		{name: "digits", value: "0a9", label: true, sub: true},
+		{name: "dots", value: "my.app.example", label: false, sub: true},
+		{name: "uppercase", value: "My-App", label: false, sub: false},
+		{name: "leading dash", value: "-app", label: false, sub: false},
+		{name: "trailing dash", value: "app-", label: false, sub: false},
+		{name: "empty label in subdomain", value: "a..b", label: false, sub: false},
+		{name: "underscore", value: "my_app", label: false, sub: false},
+		{name: "63 characters", value: strings.Repeat("a", 63), label: true, sub: true},
+		{name: "64 characters", value: strings.Repeat("a", 64), label: false, sub: true},
+		{name: "254 characters", value: strings.Repeat("a.", 126) + "ab", label: false, sub: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, valid := range map[string]bool{"label": tt.label, "sub": tt.sub} {
+				errs := violations(t, res.Schema, key+": "+tt.value+"\n")
+				if valid && len(errs) > 0 {
+					t.Errorf("%s: unexpected violations %v", key, errs)
+				}
+				if !valid && len(errs) == 0 {
+					t.Errorf("%s: %q accepted", key, tt.value)
+				}
+			}
+		})
+	}
+
+	t.Run("custom pattern", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Patterns.DNSLabel = "^[a-z]+$"
+		res := mustGenerate(t, "## @param label [dns-label] Namespace\nlabel: default\n", cfg)
+		node := property(t, res.Schema, "label")
+		if node["pattern"] != "^[a-z]+$" || node["maxLength"] != float64(63) {
+			t.Errorf("schema %v, want the custom pattern and maxLength 63", node)
+		}
+	})
+}
+
+func TestSchemaKeyOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+	}{
+		{name: "flat", values: "## @param zeta Z\nzeta: 1\n## @param alpha A\nalpha: 2\n## @param mid M\nmid: 3\n"},
+		{name: "nested", values: "## @param z.y.x X\n## @param z.b B\n## @param a.c [required] C\nz:\n  y:\n    x: 1\n  b: 2\na:\n  c: 3\n"},
+		{name: "arrays", values: "## @param list[0].zeta Z\n## @param list[0].alpha A\nlist:\n  - zeta: 1\n    alpha: 2\n"},
+		{name: "modifiers", values: "## @param name [dns-label, nullable, pattern:^x] Name\nname: x\n## @param port [percentage] Port\nport: 80\n## @param mode [string, enum:b,a] Mode\nmode: a\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := mustGenerate(t, tt.values, DefaultConfig()).Schema
+			for i := 0; i < 5; i++ {
+				if got := mustGenerate(t, tt.values, DefaultConfig()).Schema; string(got) != string(first) {
+					t.Fatalf("run %d differs:\n%s\nwant:\n%s", i, got, first)
+				}
+			}
+			if err := checkSortedKeys(json.NewDecoder(strings.NewReader(string(first)))); err != nil {
+				t.Errorf("%v in:\n%s", err, first)
+			}
+		})
+	}
+}
+
+// checkSortedKeys reads one JSON value from dec and reports the first object
+// whose keys are not in sorted order.
+func checkSortedKeys(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+		last := ""
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if key.(string) < last {
+				return fmt.Errorf("key %q after %q", key, last)
+			}
+			last = key.(string)
+			if err := checkSortedKeys(dec); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	case json.Delim('['):
+		for dec.More() {
+			if err := checkSortedKeys(dec); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	}
+	return err
+}
+
+func TestPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   string
+		conflict string
+		typ      string
+		format   interface{}
+		value    string
+		err      string
+	}{
+		{name: "string", values: "## @param p [password] P\np: s3cret\n", typ: "string", format: "password", value: "`s3cret`"},
+		{name: "empty", values: "## @param p [password] P\np: \"\"\n", typ: "string", format: "password", value: "`\"\"`"},
+		{name: "nullable", values: "## @param p [password, nullable] P\np: s3cret\n", typ: "string", format: "password", value: "`s3cret`"},
+		{name: "integer kept", values: "## @param p [password] P\np: 1234\n", typ: "integer", value: "`1234`"},
+		{name: "integer conflict", values: "## @param p [password] P\np: 1234\n", conflict: "error",
+			err: `type conflict for p: value is integer but modifier "password" implies string`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			if tt.conflict != "" {
+				cfg.TypeConflict = tt.conflict
+			}
+			if tt.err != "" {
+				_, err := Generate(Options{Values: []byte("## @section Values\n" + tt.values), Schema: true, Config: cfg})
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			res := mustGenerate(t, tt.values, cfg)
+			node := property(t, res.Schema, "p")
+			if node["type"] != tt.typ || node["format"] != tt.format {
+				t.Errorf("schema %v, want type %s and format %v", node, tt.typ, tt.format)
+			}
+			if tt.format != nil && node["writeOnly"] != true {
+				t.Errorf("schema %v, want writeOnly", node)
+			}
+			if got := tableCells(tableRow(t, res.Readme, "p"))[2]; got != tt.value {
+				t.Errorf("README value %s, want %s", got, tt.value)
+			}
+		})
+	}
+}